@@ -10,7 +10,10 @@ import (
 	"strings"
 
 	"github.com/tonghia/transaction-history/internal/args"
+	"github.com/tonghia/transaction-history/internal/config"
+	"github.com/tonghia/transaction-history/internal/parser"
 	"github.com/tonghia/transaction-history/internal/processor"
+	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
 func main() {
@@ -19,10 +22,39 @@ func main() {
 
 	// Define and parse command-line flags.
 	interactivePtr := flag.Bool("interactive", false, "Enable interactive mode to input period and file path")
-	periodPtr := flag.String("period", "", "Year and Month in YYYYMM format (required if not in interactive mode)")
-	filePathPtr := flag.String("file", "", "Path to the CSV file containing transactions (required if not in interactive mode)")
-	workernumPtr := flag.Int("workernum", 0, "Enable split file into chunk and process")
+	periodPtr := flag.String("period", "", "Period as a month (YYYYMM), year (YYYY), quarter (2023Q2), ISO week (2023W14), date range (2023-03-15..2023-04-14) a relative period such as \"last month\", \"this year\" or \"last 30 days\", or \"all\" for a summary per month (required if not in interactive mode, unless -from or -to is given)")
+	fromPtr := flag.String("from", "", "First day of the period as YYYY-MM-DD, instead of -period (optional)")
+	toPtr := flag.String("to", "", "Last day of the period as YYYY-MM-DD, instead of -period (optional)")
+	todayPtr := flag.String("today", "", "Day that relative periods are resolved against, as YYYY-MM-DD (default the current day)")
+	filePathPtr := flag.String("file", "", "Path to the CSV, XLSX, JSON/NDJSON, OFX/QFX, QIF, camt.053 XML or MT940 file, or a Mint, YNAB, Money Manager or Firefly III CSV export, containing transactions (required if not in interactive mode)")
+	workernumPtr := flag.Int("workernum", 0, "Enable split file into chunk and process (CSV and NDJSON files)")
 	outPathPtr := flag.String("out", "", "Path to the output file containing summary result in JSON format (optional)")
+	lenientPtr := flag.Bool("lenient", false, "Skip invalid rows and report them next to the summary instead of failing")
+	maxErrorsPtr := flag.Int("max-errors", 0, "Give up after this many problems in lenient mode (0 means no limit)")
+	noHeaderPtr := flag.Bool("no-header", false, "Read a CSV file without header row, laid out as -columns")
+	columnsPtr := flag.String("columns", "", "Comma-separated CSV layout of date, amount, content and skip columns (default date,amount,content)")
+	encodingPtr := flag.String("encoding", "auto", "Character encoding of the input: auto, utf-8, utf-16le, utf-16be, iso-8859-1 or windows-1252")
+	sheetPtr := flag.String("sheet", "", "Worksheet of an XLSX file to read, by name or 1-based position (default the first one)")
+	var metaFilters stringsFlag
+	flag.Var(&metaFilters, "meta", "Keep only transactions whose field has a value, as name=value (repeatable)")
+	var includeFilters, excludeFilters stringsFlag
+	flag.Var(&includeFilters, "include", "Keep only transactions whose content matches, ignoring case and diacritics: a substring, a glob with * and ?, or a regular expression prefixed with re: (repeatable)")
+	flag.Var(&excludeFilters, "exclude", "Drop transactions whose content matches, like -include (repeatable)")
+	minAmountPtr := flag.String("min-amount", "", "Keep only transactions of at least this amount, regardless of sign (optional)")
+	maxAmountPtr := flag.String("max-amount", "", "Keep only transactions of at most this amount, regardless of sign (optional)")
+	incomeOnlyPtr := flag.Bool("income-only", false, "Keep only income")
+	expenseOnlyPtr := flag.Bool("expense-only", false, "Keep only expenses")
+	wherePtr := flag.String("where", "", "Keep only transactions satisfying an expression such as 'amount < -50000 and content ~ \"rent|electric\" and weekday in (sat, sun)', over the fields date, amount, content, category, weekday and meta.<name>, where meta.<name> exists checks for a metadata key (optional)")
+	sortPtr := flag.String("sort", "", "Comma-separated sort keys, each a field with an optional :asc or :desc direction, such as amount:asc,date:desc (default date:desc)")
+	limitPtr := flag.Int("limit", 0, "Output at most this many transactions, per month for summaries per month (0 means all)")
+	offsetPtr := flag.Int("offset", 0, "Skip this many transactions before the ones to output")
+	topPtr := flag.Int("top", 0, "Output only the N largest transactions by absolute amount (optional)")
+	groupByPtr := flag.String("group-by", "", "Aggregate the transactions by the value of a field such as content, category or a metadata column into count, sum, average, min, max and share of the totals, or \"month\" for a summary per month (optional)")
+	provenancePtr := flag.Bool("with-provenance", false, "Add the source file, line and byte offset of every transaction to the output")
+	formatPtr := flag.String("format", "", "Input format: "+strings.Join(parser.FormatNames(), ", ")+" (default detected from the file extension or content)")
+	configPathPtr := flag.String("config", "", "Path to a JSON configuration file defining import profiles, the month start day and the fiscal year (optional)")
+	profilePtr := flag.String("profile", "", "Import profile of the bank the CSV file comes from, such as vcb, tcb or n26 (default detected from the header)")
+	rejectsPathPtr := flag.String("rejects", "", "Path to a CSV file receiving every rejected row of CSV, XLSX or NDJSON input with its rejection reason (optional)")
 
 	flag.Parse()
 
@@ -32,7 +64,21 @@ func main() {
 	}
 
 	// Parse command-line arguments
-	yearMonth, err := args.ParsePeriod(*periodPtr)
+	var cfg config.Config
+	if *configPathPtr != "" {
+		var err error
+		cfg, err = config.Load(*configPathPtr)
+		if err != nil {
+			log.Fatalf("Invalid config: %v", err)
+		}
+	}
+
+	today, err := args.ParseToday(*todayPtr)
+	if err != nil {
+		log.Fatalf("Invalid today: %v", err)
+	}
+
+	period, err := args.ParsePeriod(*periodPtr, *fromPtr, *toPtr, today, cfg.Calendar())
 	if err != nil {
 		log.Fatalf("Invalid period: %v", err)
 	}
@@ -42,7 +88,99 @@ func main() {
 		log.Fatalf("Invalid file path: %v", err)
 	}
 
-	summaryJSON, err := processor.Process(filePath, yearMonth, *workernumPtr)
+	metadata, err := args.ParseMetadataFilters(metaFilters)
+	if err != nil {
+		log.Fatalf("Invalid metadata filter: %v", err)
+	}
+
+	include, err := args.ParsePatterns(includeFilters)
+	if err != nil {
+		log.Fatalf("Invalid include filter: %v", err)
+	}
+
+	exclude, err := args.ParsePatterns(excludeFilters)
+	if err != nil {
+		log.Fatalf("Invalid exclude filter: %v", err)
+	}
+
+	amount, err := args.ParseAmountFilter(*minAmountPtr, *maxAmountPtr, *incomeOnlyPtr, *expenseOnlyPtr)
+	if err != nil {
+		log.Fatalf("Invalid amount filter: %v", err)
+	}
+
+	where, err := args.ParseWhere(*wherePtr)
+	if err != nil {
+		log.Fatalf("Invalid where expression: %v", err)
+	}
+
+	order, err := args.ParseSort(*sortPtr)
+	if err != nil {
+		log.Fatalf("Invalid sort order: %v", err)
+	}
+
+	page, order, err := args.ParsePage(*limitPtr, *offsetPtr, *topPtr, order)
+	if err != nil {
+		log.Fatalf("Invalid paging: %v", err)
+	}
+
+	encoding, err := parser.ParseEncoding(*encodingPtr)
+	if err != nil {
+		log.Fatalf("Invalid encoding: %v", err)
+	}
+
+	columns, err := args.ParseColumns(*columnsPtr)
+	if err != nil {
+		log.Fatalf("Invalid columns: %v", err)
+	}
+
+	opts := processor.Options{
+		Parser: parser.Options{
+			Lenient:    *lenientPtr,
+			MaxErrors:  *maxErrorsPtr,
+			Sheet:      *sheetPtr,
+			Provenance: *provenancePtr,
+			Columns:    columns,
+		},
+		NoHeader: *noHeaderPtr,
+		Encoding: encoding,
+		Metadata: metadata,
+		Include:  include,
+		Exclude:  exclude,
+		Amount:   amount,
+		Where:    where,
+		Sort:     order,
+		Page:     page,
+		GroupBy:  transaction.MetadataKey(*groupByPtr),
+		Profiles: cfg.AllProfiles(),
+		Calendar: cfg.Calendar(),
+	}
+
+	if *formatPtr != "" {
+		format, err := parser.LookupFormat(*formatPtr)
+		if err != nil {
+			log.Fatalf("Invalid format: %v", err)
+		}
+		opts.Format = format.Name
+	}
+
+	if *profilePtr != "" {
+		profile, err := cfg.Profile(*profilePtr)
+		if err != nil {
+			log.Fatalf("Invalid profile: %v", err)
+		}
+		opts.Profile = &profile
+	}
+
+	if *rejectsPathPtr != "" {
+		rejectsFile, err := os.Create(*rejectsPathPtr)
+		if err != nil {
+			log.Fatalf("Error creating rejects file: %v", err)
+		}
+		defer rejectsFile.Close()
+		opts.Parser.Rejects = parser.NewRejectWriter(rejectsFile)
+	}
+
+	summaryJSON, err := processor.Process(filePath, period, *workernumPtr, opts)
 	if err != nil {
 		log.Fatalf("Error processing CSV file: %v", err)
 	}
@@ -56,6 +194,18 @@ func main() {
 	}
 }
 
+// stringsFlag collects the values of a repeatable flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func interactiveInput(periodPtr, filePathPtr *string) {
 	// Interactive Mode: Prompt the user for period and file path.
 	fmt.Println("Interactive Mode Enabled.")
@@ -63,7 +213,7 @@ func interactiveInput(periodPtr, filePathPtr *string) {
 
 	// Prompt for Period
 	for {
-		fmt.Print("Enter the period (YYYYMM): ")
+		fmt.Print("Enter the period (YYYYMM, YYYY, YYYYQn, YYYYWnn or YYYY-MM-DD..YYYY-MM-DD): ")
 		inputPeriod, err := reader.ReadString('\n')
 		if err != nil {
 			log.Fatalf("Error reading input: %v", err)
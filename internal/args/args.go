@@ -6,15 +6,125 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/parser"
+	"github.com/tonghia/transaction-history/internal/query"
+	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
-func ParsePeriod(period string) (string, error) {
-	if period == "" {
+// columnFields are the fields a -columns spec must map, besides "skip".
+var columnFields = []string{"date", "amount", "content"}
+
+// ParsePeriod returns the period of the -period argument, or the date range
+// of the -from and -to arguments. See parser.ParsePeriod for the accepted
+// formats. Relative periods such as "last month" are resolved against
+// today. Months, quarters and years begin where the calendar has them begin.
+func ParsePeriod(period string, from string, to string, today time.Time, calendar transaction.Calendar) (transaction.Period, error) {
+	if period == "" && from == "" && to == "" {
 		flag.Usage()
-		return "", errors.New("-period argument are required")
+		return transaction.Period{}, errors.New("-period argument are required")
+	}
+
+	if from != "" || to != "" {
+		if period != "" {
+			return transaction.Period{}, errors.New("-period cannot be combined with -from or -to")
+		}
+		return parser.DateRange(from, to)
 	}
 
-	return period, nil
+	if relative, ok, err := relativePeriod(period, today, calendar); ok || err != nil {
+		return relative, err
+	}
+	return parser.ParsePeriod(period, calendar)
+}
+
+// ParseToday returns the day of the -today argument, or the current day if
+// it is empty.
+func ParseToday(today string) (time.Time, error) {
+	if today == "" {
+		return time.Now(), nil
+	}
+	return parser.ParseDate(today)
+}
+
+// relativePeriod resolves a relative period on the day today: "today",
+// "yesterday", "this", "last" or "previous" followed by week, month,
+// quarter or year, "year to date", "month to date", or "last N days" and
+// "last N weeks" ending today. It reports false for periods that are not
+// relative.
+func relativePeriod(period string, today time.Time, calendar transaction.Calendar) (transaction.Period, bool, error) {
+	words := strings.Fields(strings.ToLower(period))
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	days := func(from, to time.Time) (transaction.Period, bool, error) {
+		if from.Equal(to) {
+			relative, err := parser.ParsePeriod(from.Format("2006-01-02"), calendar)
+			return relative, true, err
+		}
+		relative, err := parser.DateRange(from.Format("2006-01-02"), to.Format("2006-01-02"))
+		return relative, true, err
+	}
+
+	switch strings.Join(words, " ") {
+	case "today":
+		return days(today, today)
+	case "yesterday":
+		return days(today.AddDate(0, 0, -1), today.AddDate(0, 0, -1))
+	case "month to date", "mtd":
+		return days(calendar.MonthOf(today).From, today)
+	case "year to date", "ytd":
+		return days(calendar.YearOf(today).From, today)
+	}
+
+	if len(words) == 3 && words[0] == "last" {
+		n, err := strconv.Atoi(words[1])
+		if err != nil {
+			return transaction.Period{}, false, nil
+		}
+		if n < 1 {
+			return transaction.Period{}, true, fmt.Errorf("invalid relative period %q: count must be positive", period)
+		}
+		switch strings.TrimSuffix(words[2], "s") {
+		case "day":
+			return days(today.AddDate(0, 0, 1-n), today)
+		case "week":
+			return days(today.AddDate(0, 0, 1-7*n), today)
+		}
+		return transaction.Period{}, true, fmt.Errorf("invalid relative period %q: expected days or weeks", period)
+	}
+
+	if len(words) != 2 {
+		return transaction.Period{}, false, nil
+	}
+	var offset int
+	switch words[0] {
+	case "this", "current":
+		offset = 0
+	case "last", "previous":
+		offset = -1
+	case "next":
+		offset = 1
+	default:
+		return transaction.Period{}, false, nil
+	}
+
+	switch words[1] {
+	case "week":
+		year, week := today.AddDate(0, 0, 7*offset).ISOWeek()
+		relative, err := parser.ParsePeriod(fmt.Sprintf("%04dW%02d", year, week), calendar)
+		return relative, true, err
+	case "month":
+		month := calendar.MonthOf(today).From.AddDate(0, offset, 0)
+		return calendar.Month(month.Year(), month.Month()), true, nil
+	case "quarter":
+		return calendar.QuarterOf(calendar.QuarterOf(today).From.AddDate(0, 3*offset, 0)), true, nil
+	case "year":
+		return calendar.YearOf(calendar.YearOf(today).From.AddDate(offset, 0, 0)), true, nil
+	}
+	return transaction.Period{}, true, fmt.Errorf("invalid relative period %q: expected week, month, quarter or year", period)
 }
 
 func ParseFilePath(filePathPtr string) (string, error) {
@@ -42,3 +152,164 @@ func ParseFilePath(filePathPtr string) (string, error) {
 
 	return absPath, nil
 }
+
+// ParseColumns parses a comma-separated -columns spec such as
+// "date,skip,amount,content,reference". Every field must appear exactly
+// once, "skip" marks columns to ignore and other names are kept as
+// metadata. Separate "debit" and "credit" columns can replace the amount.
+func ParseColumns(spec string) ([]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	columns := strings.Split(strings.ToLower(spec), ",")
+	for i, column := range columns {
+		column = strings.TrimSpace(column)
+		columns[i] = column
+		if column == "" {
+			return nil, fmt.Errorf("empty column name at position %d", i+1)
+		}
+		if column != "skip" && slices.Index(columns[:i], column) >= 0 {
+			return nil, fmt.Errorf("duplicate column '%s'", column)
+		}
+	}
+
+	if missing := parser.MissingColumn(columns, columnFields); missing != "" {
+		return nil, fmt.Errorf("missing column '%s'", missing)
+	}
+
+	return columns, nil
+}
+
+// ParseMetadataFilters parses -meta filters of the form name=value.
+func ParseMetadataFilters(filters []string) (map[string]string, error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+
+	values := make(map[string]string, len(filters))
+	for _, filter := range filters {
+		name, value, ok := strings.Cut(filter, "=")
+		name = transaction.MetadataKey(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid filter '%s', expected name=value", filter)
+		}
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// ParsePatterns parses the content patterns of -include or -exclude
+// filters. See transaction.ParsePattern for the accepted forms.
+func ParsePatterns(values []string) ([]transaction.Pattern, error) {
+	var patterns []transaction.Pattern
+	for _, value := range values {
+		if value == "" {
+			return nil, errors.New("empty content pattern")
+		}
+		pattern, err := transaction.ParsePattern(value)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// ParseAmountFilter parses the -min-amount and -max-amount bounds, which
+// may hold thousands separators, and the -income-only and -expense-only
+// flags.
+func ParseAmountFilter(minAmount string, maxAmount string, incomeOnly bool, expenseOnly bool) (transaction.AmountFilter, error) {
+	var filter transaction.AmountFilter
+	var err error
+	if filter.Min, err = parseBound("-min-amount", minAmount); err != nil {
+		return transaction.AmountFilter{}, err
+	}
+	if filter.Max, err = parseBound("-max-amount", maxAmount); err != nil {
+		return transaction.AmountFilter{}, err
+	}
+	if filter.Min != nil && filter.Max != nil && *filter.Min > *filter.Max {
+		return transaction.AmountFilter{}, fmt.Errorf("-min-amount %d is above -max-amount %d", *filter.Min, *filter.Max)
+	}
+
+	switch {
+	case incomeOnly && expenseOnly:
+		return transaction.AmountFilter{}, errors.New("-income-only cannot be combined with -expense-only")
+	case incomeOnly:
+		filter.Direction = transaction.DirectionIncome
+	case expenseOnly:
+		filter.Direction = transaction.DirectionExpense
+	}
+	return filter, nil
+}
+
+// parseBound parses an amount bound, returning nil if it is empty.
+func parseBound(name string, value string) (*int, error) {
+	value = strings.NewReplacer(",", "", "_", "", " ", "").Replace(value)
+	if value == "" {
+		return nil, nil
+	}
+	bound, err := strconv.Atoi(value)
+	if err != nil || bound < 0 {
+		return nil, fmt.Errorf("invalid %s '%s', expected an amount of zero or more", name, value)
+	}
+	return &bound, nil
+}
+
+// ParseWhere parses the -where expression, returning nil if it is empty.
+func ParseWhere(where string) (*query.Query, error) {
+	if strings.TrimSpace(where) == "" {
+		return nil, nil
+	}
+	return query.Parse(where)
+}
+
+// ParseSort parses the -sort list of keys, each a field optionally
+// followed by :asc, the default, or :desc, as in amount:asc,date:desc.
+func ParseSort(order string) ([]transaction.SortKey, error) {
+	if strings.TrimSpace(order) == "" {
+		return nil, nil
+	}
+
+	var keys []transaction.SortKey
+	for _, item := range strings.Split(order, ",") {
+		field, direction, _ := strings.Cut(item, ":")
+		key := transaction.SortKey{Field: strings.ToLower(strings.TrimSpace(field))}
+		if key.Field == "" {
+			return nil, fmt.Errorf("invalid sort key '%s', expected field[:asc|:desc]", item)
+		}
+		switch strings.ToLower(strings.TrimSpace(direction)) {
+		case "", "asc":
+		case "desc":
+			key.Desc = true
+		default:
+			return nil, fmt.Errorf("invalid sort direction '%s', expected asc or desc", direction)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// ParsePage checks the -limit, -offset and -top arguments and returns the
+// page to output. -top selects the largest transactions by absolute
+// amount, so it puts abs_amount:desc ahead of the sort order, which is
+// returned.
+func ParsePage(limit int, offset int, top int, order []transaction.SortKey) (transaction.Page, []transaction.SortKey, error) {
+	switch {
+	case limit < 0:
+		return transaction.Page{}, nil, fmt.Errorf("-limit must not be negative, got %d", limit)
+	case offset < 0:
+		return transaction.Page{}, nil, fmt.Errorf("-offset must not be negative, got %d", offset)
+	case top < 0:
+		return transaction.Page{}, nil, fmt.Errorf("-top must not be negative, got %d", top)
+	case top > 0 && limit > 0:
+		return transaction.Page{}, nil, errors.New("-top cannot be combined with -limit")
+	}
+
+	if top > 0 {
+		order = append([]transaction.SortKey{{Field: "abs_amount", Desc: true}}, order...)
+		limit = top
+	}
+	return transaction.Page{Offset: offset, Limit: limit}, order, nil
+}
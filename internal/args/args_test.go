@@ -2,27 +2,99 @@ package args
 
 import (
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
 func TestParsePeriodWithValidInput(t *testing.T) {
 	input := "2023-10"
-	expected := "2023-10"
+	expected := "2023/10"
+
+	result, err := ParsePeriod(input, "", "", time.Now(), transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if result.Label != expected {
+		t.Errorf("expected %v, got %v", expected, result.Label)
+	}
+}
 
-	result, err := ParsePeriod(input)
+// Build an open-ended range from -from alone, rejecting it next to -period
+func TestParsePeriodWithFromTo(t *testing.T) {
+	result, err := ParsePeriod("", "2023-03-15", "", time.Now(), transaction.Calendar{})
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
+	if result.Label != "2023/03/15.." || !result.To.IsZero() {
+		t.Errorf("expected an open range from 2023/03/15, got %v", result)
+	}
+
+	if _, err := ParsePeriod("202303", "2023-03-15", "", time.Now(), transaction.Calendar{}); err == nil {
+		t.Errorf("expected an error for -period with -from, got nil")
+	}
+}
 
-	if result != expected {
-		t.Errorf("expected %v, got %v", expected, result)
+// Resolve relative periods against the given day
+func TestParsePeriodRelative(t *testing.T) {
+	today := time.Date(2024, 1, 10, 15, 4, 0, 0, time.Local)
+	tests := map[string]string{
+		"last month":       "2023/12",
+		"This Year":        "2024",
+		"previous quarter": "2023/Q4",
+		"next quarter":     "2024/Q2",
+		"last week":        "2024/W01",
+		"yesterday":        "2024/01/09",
+		"last 30 days":     "2023/12/12..2024/01/10",
+		"year to date":     "2024/01/01..2024/01/10",
+	}
+	for input, expected := range tests {
+		result, err := ParsePeriod(input, "", "", today, transaction.Calendar{})
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", input, err)
+			continue
+		}
+		if result.Label != expected {
+			t.Errorf("%s: expected %v, got %v", input, expected, result.Label)
+		}
+	}
+
+	for _, input := range []string{"last 0 days", "last 3 fortnights", "this decade"} {
+		if _, err := ParsePeriod(input, "", "", today, transaction.Calendar{}); err == nil {
+			t.Errorf("%s: expected an error, got nil", input)
+		}
+	}
+}
+
+// Resolve relative periods on a fiscal calendar
+func TestParsePeriodRelativeFiscal(t *testing.T) {
+	today := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+	calendar := transaction.Calendar{MonthStartDay: 25, YearStartMonth: time.April}
+	tests := map[string]string{
+		"this month":    "2023/12",
+		"last quarter":  "FY2023/Q2",
+		"this year":     "FY2023",
+		"month to date": "2023/12/25..2024/01/10",
+	}
+	for input, expected := range tests {
+		result, err := ParsePeriod(input, "", "", today, calendar)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", input, err)
+			continue
+		}
+		if result.Label != expected {
+			t.Errorf("%s: expected %v, got %v", input, expected, result.Label)
+		}
 	}
 }
 
 func TestParsePeriodWithEmptyInput(t *testing.T) {
 	input := ""
 
-	_, err := ParsePeriod(input)
+	_, err := ParsePeriod(input, "", "", time.Now(), transaction.Calendar{})
 	if err == nil {
 		t.Fatal("expected an error, got none")
 	}
@@ -57,3 +129,96 @@ func TestParseFilePathEmpty(t *testing.T) {
 		t.Errorf("expected error message %q, got %q", expectedError, err.Error())
 	}
 }
+
+// Parse a column spec with skipped and metadata columns
+func TestParseColumnsValid(t *testing.T) {
+	columns, err := ParseColumns("Date, skip,amount,content,skip,Channel")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []string{"date", "skip", "amount", "content", "skip", "channel"}
+	if !reflect.DeepEqual(columns, expected) {
+		t.Errorf("expected %v, got %v", expected, columns)
+	}
+}
+
+// Reject column specs with empty, missing or duplicate columns
+func TestParseColumnsInvalid(t *testing.T) {
+	for _, spec := range []string{"date,amount,,content", "date,amount", "date,amount,content,date", "date,amount,content,ref,ref"} {
+		if _, err := ParseColumns(spec); err == nil {
+			t.Errorf("expected an error for %q, got nil", spec)
+		}
+	}
+}
+
+// Accept debit and credit columns in place of the amount
+func TestParseColumnsDebitCredit(t *testing.T) {
+	if _, err := ParseColumns("date,debit,credit,content"); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
+
+// Parse amount bounds with separators and reject conflicting directions
+func TestParseAmountFilter(t *testing.T) {
+	filter, err := ParseAmountFilter("1,000,000", "", false, true)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if filter.Min == nil || *filter.Min != 1000000 || filter.Max != nil || filter.Direction != transaction.DirectionExpense {
+		t.Errorf("expected expenses of at least 1000000, got %+v", filter)
+	}
+
+	filter, err = ParseAmountFilter("", "0", false, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if filter.Min != nil || filter.Max == nil || *filter.Max != 0 {
+		t.Errorf("expected a maximum of 0, got %+v", filter)
+	}
+
+	for _, bounds := range [][2]string{{"-5", ""}, {"abc", ""}, {"200", "100"}, {"1", "0"}} {
+		if _, err := ParseAmountFilter(bounds[0], bounds[1], false, false); err == nil {
+			t.Errorf("expected an error for bounds %v, got nil", bounds)
+		}
+	}
+	if _, err := ParseAmountFilter("", "", true, true); err == nil {
+		t.Errorf("expected an error for -income-only with -expense-only, got nil")
+	}
+}
+
+// Parse sort keys with optional directions
+func TestParseSort(t *testing.T) {
+	keys, err := ParseSort("Amount:ASC, date:desc,content")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []transaction.SortKey{{Field: "amount"}, {Field: "date", Desc: true}, {Field: "content"}}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+
+	for _, order := range []string{"amount:up", "amount,,date"} {
+		if _, err := ParseSort(order); err == nil {
+			t.Errorf("expected an error for %q, got nil", order)
+		}
+	}
+}
+
+// Turn -top into a limit on the largest amounts first
+func TestParsePage(t *testing.T) {
+	page, order, err := ParsePage(0, 10, 5, []transaction.SortKey{{Field: "date"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expectedOrder := []transaction.SortKey{{Field: "abs_amount", Desc: true}, {Field: "date"}}
+	if page != (transaction.Page{Offset: 10, Limit: 5}) || !reflect.DeepEqual(order, expectedOrder) {
+		t.Errorf("expected offset 10, limit 5 and order %v, got %v and %v", expectedOrder, page, order)
+	}
+
+	if _, _, err := ParsePage(10, 0, 5, nil); err == nil {
+		t.Errorf("expected an error for -top with -limit, got nil")
+	}
+	if _, _, err := ParsePage(-1, 0, 0, nil); err == nil {
+		t.Errorf("expected an error for a negative limit, got nil")
+	}
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tonghia/transaction-history/internal/parser"
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Config holds the settings read from a configuration file.
+type Config struct {
+	// Profiles are import profiles added to the built-in ones. A profile
+	// named like a built-in one replaces it.
+	Profiles []Profile `json:"profiles"`
+	// MonthStartDay is the day of the month budget months begin on, from 1
+	// to 28, 1 when zero.
+	MonthStartDay int `json:"month_start_day,omitempty"`
+	// FiscalYearStartMonth is the month, from 1 to 12, fiscal years begin
+	// in, January when zero.
+	FiscalYearStartMonth int `json:"fiscal_year_start_month,omitempty"`
+}
+
+// Profile holds the import settings of the CSV exports of a bank.
+type Profile struct {
+	Name string `json:"name"`
+	// Delimiter separates the fields of a record, "," when empty.
+	Delimiter string `json:"delimiter,omitempty"`
+	// DateLayout is a Go time layout such as "02/01/2006".
+	DateLayout string `json:"date_layout,omitempty"`
+	// ThousandsSeparator is removed from amounts.
+	ThousandsSeparator string `json:"thousands_separator,omitempty"`
+	// DecimalSeparator allows amounts written with a zero fraction.
+	DecimalSeparator string `json:"decimal_separator,omitempty"`
+	// Sign is "signed" when expenses are negative, the default, or
+	// "inverted" when they are positive.
+	Sign string `json:"sign,omitempty"`
+	// Encoding of the exports, used unless -encoding is given.
+	Encoding string `json:"encoding,omitempty"`
+	// Columns maps header names to the fields date, amount, content, debit,
+	// credit or skip. Unmapped columns are kept as metadata. The header
+	// names also identify the exports of the bank.
+	Columns map[string]string `json:"columns"`
+}
+
+// Sign conventions of a Profile.
+const (
+	SignSigned   = "signed"
+	SignInverted = "inverted"
+)
+
+// requiredFields are the fields a Profile must map columns to.
+var requiredFields = []string{"date", "amount", "content"}
+
+// builtinProfiles are the profiles available without a configuration file.
+var builtinProfiles = []Profile{
+	{
+		Name:               "vcb",
+		DateLayout:         "02/01/2006",
+		ThousandsSeparator: ",",
+		Columns: map[string]string{
+			"ngày giao dịch":  "date",
+			"số tham chiếu":   "reference",
+			"số tiền ghi nợ":  parser.DebitColumn,
+			"số tiền ghi có":  parser.CreditColumn,
+			"mô tả giao dịch": "content",
+		},
+	},
+	{
+		Name:               "tcb",
+		DateLayout:         "02/01/2006",
+		ThousandsSeparator: ",",
+		Columns: map[string]string{
+			"ngày giao dịch": "date",
+			"diễn giải":      "content",
+			"nợ":             parser.DebitColumn,
+			"có":             parser.CreditColumn,
+			"số dư":          "balance",
+		},
+	},
+	{
+		Name:             "n26",
+		DateLayout:       "2006-01-02",
+		DecimalSeparator: ".",
+		Columns: map[string]string{
+			"booking date":      "date",
+			"partner name":      "content",
+			"amount (eur)":      "amount",
+			"partner iban":      "iban",
+			"type":              "type",
+			"payment reference": "reference",
+		},
+	},
+}
+
+// Load reads a JSON configuration file and validates its profiles.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("error reading config file: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("error parsing config file: %v", err)
+	}
+
+	if config.MonthStartDay < 0 || config.MonthStartDay > 28 {
+		return Config{}, fmt.Errorf("month_start_day must be between 1 and 28, got %d", config.MonthStartDay)
+	}
+	if config.FiscalYearStartMonth < 0 || config.FiscalYearStartMonth > 12 {
+		return Config{}, fmt.Errorf("fiscal_year_start_month must be between 1 and 12, got %d", config.FiscalYearStartMonth)
+	}
+
+	for i := range config.Profiles {
+		profile := &config.Profiles[i]
+		if err := profile.normalize(); err != nil {
+			return Config{}, fmt.Errorf("invalid profile '%s': %v", profile.Name, err)
+		}
+		for _, other := range config.Profiles[:i] {
+			if other.Name == profile.Name {
+				return Config{}, fmt.Errorf("duplicate profile '%s'", profile.Name)
+			}
+		}
+	}
+
+	return config, nil
+}
+
+// Calendar returns the calendar of the month start day and fiscal year.
+func (c Config) Calendar() transaction.Calendar {
+	return transaction.Calendar{
+		MonthStartDay:  c.MonthStartDay,
+		YearStartMonth: time.Month(c.FiscalYearStartMonth),
+	}
+}
+
+// AllProfiles returns the profiles of the configuration followed by the
+// built-in ones it does not replace.
+func (c Config) AllProfiles() []Profile {
+	profiles := slices.Clone(c.Profiles)
+	for _, builtin := range builtinProfiles {
+		if !slices.ContainsFunc(c.Profiles, func(p Profile) bool { return p.Name == builtin.Name }) {
+			profiles = append(profiles, builtin)
+		}
+	}
+	return profiles
+}
+
+// Profile returns the profile with the given name.
+func (c Config) Profile(name string) (Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, profile := range c.AllProfiles() {
+		if profile.Name == name {
+			return profile, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("unknown profile '%s'", name)
+}
+
+// DetectProfile returns the first profile whose column names all appear in
+// the header line.
+func DetectProfile(profiles []Profile, header string) (Profile, bool) {
+	for _, profile := range profiles {
+		names, err := profile.HeaderNames(header)
+		if err != nil {
+			continue
+		}
+		matches := true
+		for name := range profile.Columns {
+			if !slices.Contains(names, name) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
+
+// HeaderNames splits a header line into normalized column names.
+func (p Profile) HeaderNames(header string) ([]string, error) {
+	reader := csv.NewReader(strings.NewReader(header))
+	reader.TrimLeadingSpace = true
+	reader.Comma = p.Comma()
+	names, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = normalizeName(name)
+	}
+	return names, nil
+}
+
+// Field returns the field held by the column with a normalized header
+// name: the mapped one, or the name itself for metadata.
+func (p Profile) Field(name string) string {
+	if field, ok := p.Columns[name]; ok {
+		return field
+	}
+	return name
+}
+
+// Comma returns the field delimiter of the profile.
+func (p Profile) Comma() rune {
+	if p.Delimiter == "" {
+		return ','
+	}
+	r, _ := utf8.DecodeRuneInString(p.Delimiter)
+	return r
+}
+
+// ParserOptions applies the format settings of the profile to opts.
+func (p Profile) ParserOptions(opts parser.Options) parser.Options {
+	opts.Comma = p.Comma()
+	opts.DateLayout = p.DateLayout
+	opts.ThousandsSeparator = p.ThousandsSeparator
+	opts.DecimalSeparator = p.DecimalSeparator
+	opts.InvertSign = p.Sign == SignInverted
+	return opts
+}
+
+// normalize lowercases the names of a profile read from a file and checks
+// its settings.
+func (p *Profile) normalize() error {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	if p.Name == "" {
+		return fmt.Errorf("missing name")
+	}
+	if p.Delimiter != "" && (utf8.RuneCountInString(p.Delimiter) != 1 || p.Delimiter == "\"" || p.Delimiter == "\n") {
+		return fmt.Errorf("delimiter must be a single character other than a quote or newline")
+	}
+	if p.Sign != "" && p.Sign != SignSigned && p.Sign != SignInverted {
+		return fmt.Errorf("sign must be '%s' or '%s'", SignSigned, SignInverted)
+	}
+	if p.Encoding != "" {
+		encoding, err := parser.ParseEncoding(p.Encoding)
+		if err != nil {
+			return err
+		}
+		p.Encoding = encoding
+	}
+
+	columns := make(map[string]string, len(p.Columns))
+	var fields []string
+	for name, field := range p.Columns {
+		name, field = normalizeName(name), strings.ToLower(strings.TrimSpace(field))
+		if name == "" || field == "" {
+			return fmt.Errorf("empty column name")
+		}
+		if _, ok := columns[name]; ok {
+			return fmt.Errorf("duplicate column '%s'", name)
+		}
+		if field != parser.SkipColumn && slices.Contains(fields, field) {
+			return fmt.Errorf("several columns hold '%s'", field)
+		}
+		columns[name] = field
+		fields = append(fields, field)
+	}
+	if missing := parser.MissingColumn(fields, requiredFields); missing != "" {
+		return fmt.Errorf("no column holds '%s'", missing)
+	}
+	p.Columns = columns
+
+	return nil
+}
+
+// normalizeName makes header names compare regardless of case and spacing.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
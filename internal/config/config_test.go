@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/parser"
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Loads profiles from a file, replacing the built-in one of the same name
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	content := `{"profiles": [{
+		"name": "VCB",
+		"delimiter": ";",
+		"date_layout": "2006-01-02",
+		"sign": "inverted",
+		"encoding": "cp1252",
+		"columns": {"Booking  Date": "date", "Amount": "amount", "Text": "Content", "Balance": "skip"}
+	}]}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	profile, err := config.Profile("vcb")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := Profile{
+		Name:       "vcb",
+		Delimiter:  ";",
+		DateLayout: "2006-01-02",
+		Sign:       SignInverted,
+		Encoding:   parser.EncodingWindows1252,
+		Columns:    map[string]string{"booking date": "date", "amount": "amount", "text": "content", "balance": "skip"},
+	}
+	if !reflect.DeepEqual(profile, expected) {
+		t.Errorf("expected %v, got %v", expected, profile)
+	}
+	if len(config.AllProfiles()) != len(builtinProfiles) {
+		t.Errorf("expected %d profiles, got %d", len(builtinProfiles), len(config.AllProfiles()))
+	}
+
+	opts := profile.ParserOptions(parser.Options{Lenient: true})
+	if opts.Comma != ';' || opts.DateLayout != "2006-01-02" || !opts.InvertSign || !opts.Lenient {
+		t.Errorf("unexpected parser options %+v", opts)
+	}
+}
+
+// Rejects profiles without name, with missing fields or unknown settings
+func TestLoadInvalid(t *testing.T) {
+	profiles := []string{
+		`{"columns": {"d": "date", "a": "amount", "c": "content"}}`,
+		`{"name": "x", "columns": {"d": "date", "c": "content"}}`,
+		`{"name": "x", "columns": {"d": "date", "a": "amount", "b": "amount", "c": "content"}}`,
+		`{"name": "x", "sign": "negative", "columns": {"d": "date", "a": "amount", "c": "content"}}`,
+		`{"name": "x", "delimiter": "ab", "columns": {"d": "date", "a": "amount", "c": "content"}}`,
+		`{"name": "x", "encoding": "ebcdic", "columns": {"d": "date", "a": "amount", "c": "content"}}`,
+	}
+	for _, profile := range profiles {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(`{"profiles": [`+profile+`]}`), 0o644); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("expected an error for %s, got nil", profile)
+		}
+	}
+}
+
+// Loads the month start day and fiscal year, rejecting days past the 28th
+func TestLoadCalendar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"month_start_day": 25, "fiscal_year_start_month": 4}`), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	config, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := transaction.Calendar{MonthStartDay: 25, YearStartMonth: time.April}
+	if config.Calendar() != expected {
+		t.Errorf("expected %v, got %v", expected, config.Calendar())
+	}
+
+	if err := os.WriteFile(path, []byte(`{"month_start_day": 31}`), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Errorf("expected an error for month_start_day 31, got nil")
+	}
+}
+
+// Detects the profile of a bank from the names in its header
+func TestDetectProfile(t *testing.T) {
+	header := "Ngày giao dịch,Số tham chiếu,Số tiền ghi nợ,Số tiền ghi có,Mô tả giao dịch\n"
+	profile, ok := DetectProfile(Config{}.AllProfiles(), header)
+	if !ok || profile.Name != "vcb" {
+		t.Errorf("expected profile vcb, got %v", profile.Name)
+	}
+
+	if _, ok := DetectProfile(Config{}.AllProfiles(), "date,amount,content\n"); ok {
+		t.Errorf("expected no profile for the default header")
+	}
+}
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseDecimalAmount parses a decimal amount such as "-1234" or "-1234.00".
+// Transaction amounts are kept in whole currency units, so amounts with a
+// fraction other than zero are rejected rather than rounded.
+func parseDecimalAmount(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	whole, fraction, _ := strings.Cut(s, ".")
+	if strings.Trim(fraction, "0123456789") != "" || (whole == "" && fraction == "") {
+		return 0, fmt.Errorf("invalid decimal amount %q", s)
+	}
+	if strings.Trim(fraction, "0") != "" {
+		return 0, fmt.Errorf("amount %q has a fraction, expected whole units", s)
+	}
+	if whole == "" || whole == "-" || whole == "+" {
+		whole += "0"
+	}
+	amount, err := strconv.Atoi(whole)
+	if err != nil {
+		return 0, fmt.Errorf("invalid decimal amount %q", s)
+	}
+	return amount, nil
+}
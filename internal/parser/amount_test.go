@@ -0,0 +1,37 @@
+package parser
+
+import (
+	"testing"
+)
+
+// Parses whole decimal amounts and rejects fractions instead of rounding them
+func TestParseDecimalAmount(t *testing.T) {
+	valid := map[string]int{
+		"-1234":    -1234,
+		" 1234.00": 1234,
+		"+5.":      5,
+		"-.0":      0,
+	}
+	for input, expected := range valid {
+		amount, err := parseDecimalAmount(input)
+		if err != nil || amount != expected {
+			t.Errorf("%q: expected %d, got %d, %v", input, expected, amount, err)
+		}
+	}
+
+	for _, input := range []string{"-12.60", "0.01", "1.2.3", "", ".", "12a", "1e3"} {
+		if amount, err := parseDecimalAmount(input); err == nil {
+			t.Errorf("%q: expected an error, got %d", input, amount)
+		}
+	}
+}
+
+// Rejects cents in app exports as row problems
+func TestParseMoneyFraction(t *testing.T) {
+	if amount, err := parseMoney("$1,234.00"); err != nil || amount != 1234 {
+		t.Errorf("expected 1234, got %d, %v", amount, err)
+	}
+	if amount, err := parseMoney("$4.50"); err == nil {
+		t.Errorf("expected an error for $4.50, got %d", amount)
+	}
+}
@@ -0,0 +1,369 @@
+package parser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// appExport describes the CSV export of a personal-finance app, whose
+// columns are found by header name.
+type appExport struct {
+	name string
+	// required are the header names the export must have, which also
+	// identify its files.
+	required []string
+	// convert validates a record, by lowercase header name, and converts it
+	// into a Transaction.
+	convert func(row appRow) (transaction.Transaction, []RowError)
+}
+
+// appRow is a record of an app export by lowercase header name.
+type appRow struct {
+	fields map[string]string
+	line   int
+}
+
+// get returns the value of the first of the columns present in the row.
+func (r appRow) get(columns ...string) string {
+	for _, column := range columns {
+		if value, ok := r.fields[column]; ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// problem returns a RowError for the value of a column.
+func (r appRow) problem(column string, reason string) RowError {
+	return RowError{Line: r.line, Column: column, Value: r.fields[column], Reason: reason}
+}
+
+var (
+	mintExport = appExport{
+		name:     "mint",
+		required: []string{"date", "description", "amount", "transaction type"},
+		convert:  mintToTransaction,
+	}
+	ynabExport = appExport{
+		name:     "ynab",
+		required: []string{"date", "payee", "outflow", "inflow"},
+		convert:  ynabToTransaction,
+	}
+	moneyManagerExport = appExport{
+		name:     "moneymanager",
+		required: []string{"category", "note", "amount", "income/expense"},
+		convert:  moneyManagerToTransaction,
+	}
+	fireflyExport = appExport{
+		name:     "firefly",
+		required: []string{"date", "type", "amount", "description", "source_name", "destination_name"},
+		convert:  fireflyToTransaction,
+	}
+)
+
+func init() {
+	for _, export := range []appExport{mintExport, ynabExport, moneyManagerExport, fireflyExport} {
+		Register(Format{
+			Name:       export.name,
+			Extensions: []string{".csv"},
+			Magic:      export.matches,
+			Rejects:    true,
+			Importer:   export,
+		})
+	}
+}
+
+// matches reports whether the first line of head is the header of the export.
+func (e appExport) matches(head []byte) bool {
+	reader := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(head), "\ufeff")))
+	reader.TrimLeadingSpace = true
+	names, err := reader.Read()
+	if err != nil {
+		return false
+	}
+	return e.missingColumn(normalizeHeader(names)) == ""
+}
+
+func (e appExport) missingColumn(names []string) string {
+	for _, column := range e.required {
+		if !slices.Contains(names, column) {
+			return column
+		}
+	}
+	return ""
+}
+
+// Read reads the records of the export, checking its header first.
+func (e appExport) Read(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	reader, raw := newRecordReader(file, opts)
+
+	header, err := reader.Read()
+	if err != nil {
+		return []transaction.Transaction{}, nil, fmt.Errorf("error reading %s header: %v", e.name, err)
+	}
+	names := normalizeHeader(header)
+	if missing := e.missingColumn(names); missing != "" {
+		return []transaction.Transaction{}, nil, fmt.Errorf("unexpected %s header: missing column '%s'", e.name, missing)
+	}
+
+	return readRecords(reader, raw, opts, func(record []string, line int) (transaction.Transaction, []RowError) {
+		row := appRow{fields: map[string]string{}, line: line}
+		for i, name := range names {
+			// Keep the first of columns with the same name.
+			if _, ok := row.fields[name]; !ok {
+				row.fields[name] = record[i]
+			}
+		}
+		tx, problems := e.convert(row)
+		if len(problems) == 0 && strings.TrimSpace(tx.Content) == "" {
+			problems = []RowError{{Line: row.line, Reason: "empty content"}}
+		}
+		return tx, problems
+	})
+}
+
+// mintToTransaction converts a Mint record, whose amounts are positive
+// with the direction given by the transaction type.
+func mintToTransaction(row appRow) (transaction.Transaction, []RowError) {
+	var problems []RowError
+
+	date, err := parseAppDate(row.get("date"), "1/2/2006", "2006-01-02")
+	if err != nil {
+		problems = append(problems, row.problem("date", fmt.Sprintf("invalid date format: %v", err)))
+	}
+
+	amount, err := parseMoney(row.get("amount"))
+	if err != nil {
+		problems = append(problems, row.problem("amount", fmt.Sprintf("invalid amount: %v", err)))
+	}
+	switch strings.ToLower(row.get("transaction type")) {
+	case "debit":
+		amount = -absInt(amount)
+	case "credit":
+		amount = absInt(amount)
+	default:
+		problems = append(problems, row.problem("transaction type", "expected debit or credit"))
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	return transaction.Transaction{
+		Date:     date,
+		Amount:   amount,
+		Content:  joinContent(row.get("description"), row.get("notes")),
+		Category: row.get("category"),
+		Metadata: appMetadata(row, map[string]string{"account": "account name", "labels": "labels"}),
+	}, nil
+}
+
+// ynabToTransaction converts a YNAB register record with separate outflow
+// and inflow columns.
+func ynabToTransaction(row appRow) (transaction.Transaction, []RowError) {
+	var problems []RowError
+
+	date, err := parseAppDate(row.get("date"), "1/2/2006", "2006-01-02", "02/01/2006")
+	if err != nil {
+		problems = append(problems, row.problem("date", fmt.Sprintf("invalid date format: %v", err)))
+	}
+
+	amount := 0
+	for _, column := range []string{"outflow", "inflow"} {
+		value := row.get(column)
+		if value == "" {
+			continue
+		}
+		money, err := parseMoney(value)
+		if err != nil {
+			problems = append(problems, row.problem(column, fmt.Sprintf("invalid amount: %v", err)))
+			continue
+		}
+		if column == "outflow" {
+			money = -money
+		}
+		amount += money
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	return transaction.Transaction{
+		Date:     date,
+		Amount:   amount,
+		Content:  joinContent(row.get("payee"), row.get("memo")),
+		Category: row.get("category group/category", "category"),
+		Metadata: appMetadata(row, map[string]string{"account": "account", "flag": "flag"}),
+	}, nil
+}
+
+// moneyManagerToTransaction converts a Money Manager record, whose amounts
+// are positive with the direction given by the income/expense column.
+func moneyManagerToTransaction(row appRow) (transaction.Transaction, []RowError) {
+	var problems []RowError
+
+	column := "period"
+	if _, ok := row.fields[column]; !ok {
+		column = "date"
+	}
+	date, err := parseAppDate(row.get(column), "2006-01-02 15:04:05", "2006-01-02", "01/02/2006 15:04:05", "1/2/2006")
+	if err != nil {
+		problems = append(problems, row.problem(column, fmt.Sprintf("invalid date format: %v", err)))
+	}
+
+	amount, err := parseMoney(row.get("amount"))
+	if err != nil {
+		problems = append(problems, row.problem("amount", fmt.Sprintf("invalid amount: %v", err)))
+	}
+	switch strings.ToLower(row.get("income/expense")) {
+	case "exp.", "expense", "transfer-out":
+		amount = -absInt(amount)
+	case "income", "transfer-in":
+		amount = absInt(amount)
+	default:
+		problems = append(problems, row.problem("income/expense", "expected Income, Exp., Transfer-In or Transfer-Out"))
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	return transaction.Transaction{
+		Date:     date,
+		Amount:   amount,
+		Content:  joinContent(row.get("note"), row.get("description")),
+		Category: row.get("category"),
+		Metadata: appMetadata(row, map[string]string{"account": "accounts", "subcategory": "subcategory"}),
+	}, nil
+}
+
+// fireflyToTransaction converts a Firefly III record. Withdrawals are
+// expenses and deposits income, other types keep the sign of the amount.
+func fireflyToTransaction(row appRow) (transaction.Transaction, []RowError) {
+	var problems []RowError
+
+	date, err := parseAppDate(row.get("date"), time.RFC3339, "2006-01-02")
+	if err != nil {
+		problems = append(problems, row.problem("date", fmt.Sprintf("invalid date format: %v", err)))
+	}
+
+	amount, err := parseMoney(row.get("amount"))
+	if err != nil {
+		problems = append(problems, row.problem("amount", fmt.Sprintf("invalid amount: %v", err)))
+	}
+
+	metadata := map[string]string{"account": "source_name", "counterparty": "destination_name", "budget": "budget", "tags": "tags"}
+	switch strings.ToLower(row.get("type")) {
+	case "withdrawal":
+		amount = -absInt(amount)
+	case "deposit":
+		amount = absInt(amount)
+		metadata["account"], metadata["counterparty"] = "destination_name", "source_name"
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	return transaction.Transaction{
+		Date:     date,
+		Amount:   amount,
+		Content:  joinContent(row.get("description"), row.get("notes")),
+		Category: row.get("category"),
+		Metadata: appMetadata(row, metadata),
+	}, nil
+}
+
+// normalizeHeader lowercases and trims header names.
+func normalizeHeader(names []string) []string {
+	normalized := make([]string, len(names))
+	for i, name := range names {
+		normalized[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	return normalized
+}
+
+// parseAppDate parses a date in the first matching layout and returns it
+// in the YYYY/MM/DD layout of the output.
+func parseAppDate(s string, layouts ...string) (string, error) {
+	var err error
+	for _, layout := range layouts {
+		var date time.Time
+		if date, err = time.Parse(layout, s); err == nil {
+			return date.Format("2006/01/02"), nil
+		}
+	}
+	return "", err
+}
+
+// parseMoney parses an amount as written by the apps, such as "$1,234.50",
+// "-$5.00" or "(5.00)" for negative amounts.
+func parseMoney(s string) (int, error) {
+	value := strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
+		negative = true
+		value = value[1 : len(value)-1]
+	}
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == '-', r == '+':
+			return r
+		case r == ',', r == ' ', strings.ContainsRune("$€£¥₫", r):
+			return -1
+		}
+		return r
+	}, value)
+
+	amount, err := parseDecimalAmount(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid money amount %q: %v", s, err)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// appMetadata returns the non-empty values of the row columns by metadata
+// key.
+func appMetadata(row appRow, columns map[string]string) map[string]string {
+	var metadata map[string]string
+	for key, column := range columns {
+		value := row.get(column)
+		if value == "" {
+			continue
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// joinContent combines a payee and a memo into the content of a
+// transaction.
+func joinContent(payee string, memo string) string {
+	switch {
+	case memo == "" || memo == payee:
+		return payee
+	case payee == "":
+		return memo
+	}
+	return payee + " - " + memo
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
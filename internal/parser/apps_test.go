@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Reads a Mint export, signing amounts by transaction type
+func TestReadMint(t *testing.T) {
+	content := `"Date","Description","Original Description","Amount","Transaction Type","Category","Account Name","Labels","Notes"
+"1/15/2023","Coffee Shop","COFFEE SHOP #12","4.00","debit","Coffee Shops","Visa","",""
+"1/31/2023","Employer","EMPLOYER PAYROLL","2,500.00","credit","Paycheck","Checking","","January"
+"2/01/2023","Rent","RENT","1200","transfer","Rent","Checking","",""
+`
+	transactions, rowErrors, err := mintExport.Read(strings.NewReader(content), Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/01/15", Amount: -4, Content: "Coffee Shop", Category: "Coffee Shops", Metadata: map[string]string{"account": "Visa"}},
+		{Date: "2023/01/31", Amount: 2500, Content: "Employer - January", Category: "Paycheck", Metadata: map[string]string{"account": "Checking"}},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 4 || rowErrors[0].Column != "transaction type" {
+		t.Errorf("expected a transaction type error at line 4, got %v", rowErrors)
+	}
+}
+
+// Reads a YNAB register export with outflow and inflow columns
+func TestReadYNAB(t *testing.T) {
+	content := "\"Account\",\"Flag\",\"Date\",\"Payee\",\"Category Group/Category\",\"Category Group\",\"Category\",\"Memo\",\"Outflow\",\"Inflow\",\"Cleared\"\n" +
+		"\"Checking\",\"\",\"03/02/2023\",\"Grocery Store\",\"Everyday: Groceries\",\"Everyday\",\"Groceries\",\"weekly\",\"$85.00\",\"$0.00\",\"Cleared\"\n" +
+		"\"Checking\",\"Red\",\"03/05/2023\",\"Employer\",\"Inflow: Ready to Assign\",\"Inflow\",\"Ready to Assign\",\"\",\"$0.00\",\"$3,000.00\",\"Cleared\"\n"
+
+	transactions, _, err := ynabExport.Read(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/03/02", Amount: -85, Content: "Grocery Store - weekly", Category: "Everyday: Groceries", Metadata: map[string]string{"account": "Checking"}},
+		{Date: "2023/03/05", Amount: 3000, Content: "Employer", Category: "Inflow: Ready to Assign", Metadata: map[string]string{"account": "Checking", "flag": "Red"}},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Reads a Money Manager export, signing amounts by income or expense
+func TestReadMoneyManager(t *testing.T) {
+	content := `Period,Accounts,Category,Subcategory,Note,VND,Income/Expense,Description,Amount,Currency,Accounts
+2023-04-01 12:30:00,Cash,Food,Lunch,Bún chả,45000,Exp.,with team,45000,VND,45000
+2023-04-05 09:00:00,Bank,Salary,,Company,20000000,Income,,20000000,VND,20000000
+`
+	transactions, _, err := moneyManagerExport.Read(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/04/01", Amount: -45000, Content: "Bún chả - with team", Category: "Food", Metadata: map[string]string{"account": "Cash", "subcategory": "Lunch"}},
+		{Date: "2023/04/05", Amount: 20000000, Content: "Company", Category: "Salary", Metadata: map[string]string{"account": "Bank"}},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Reads a Firefly III export, signing amounts by transaction type
+func TestReadFirefly(t *testing.T) {
+	content := `user_id,group_id,journal_id,type,amount,description,date,source_name,destination_name,category,budget,tags,notes
+1,10,10,Withdrawal,-42.00,Electricity,2023-05-03T00:00:00+02:00,Checking,Power Co,Utilities,Bills,,
+1,11,11,Deposit,1500.00,Salary,2023-05-25T00:00:00+02:00,Employer,Checking,Income,,"work,monthly",May
+`
+	transactions, _, err := fireflyExport.Read(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/05/03", Amount: -42, Content: "Electricity", Category: "Utilities", Metadata: map[string]string{"account": "Checking", "counterparty": "Power Co", "budget": "Bills"}},
+		{Date: "2023/05/25", Amount: 1500, Content: "Salary - May", Category: "Income", Metadata: map[string]string{"account": "Checking", "counterparty": "Employer", "tags": "work,monthly"}},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Tells app exports apart from plain CSV files by their header
+func TestDetectAppFormat(t *testing.T) {
+	tests := map[string]string{
+		"\xef\xbb\xbf\"Account\",\"Flag\",\"Date\",\"Payee\",\"Memo\",\"Outflow\",\"Inflow\"\n": "ynab",
+		"Date,Description,Amount,Transaction Type\n":                                            "mint",
+		"date,amount,content\n": "csv",
+	}
+	for head, expected := range tests {
+		if format := DetectFormat("export.csv", []byte(head)); format.Name != expected {
+			t.Errorf("%q: expected %s, got %s", head, expected, format.Name)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// camtEntry is the part of an ISO 20022 Ntry element mapped to a Transaction.
+type camtEntry struct {
+	Amount         string   `xml:"Amt"`
+	CreditDebit    string   `xml:"CdtDbtInd"`
+	BookingDate    string   `xml:"BookgDt>Dt"`
+	BookingTime    string   `xml:"BookgDt>DtTm"`
+	Remittance     []string `xml:"NtryDtls>TxDtls>RmtInf>Ustrd"`
+	AdditionalInfo string   `xml:"AddtlNtryInf"`
+}
+
+// ReadCAMT053 reads the entries of an ISO 20022 camt.053 statement. The
+// document is streamed one Ntry element at a time, so its size does not
+// matter, and every statement of a multi-statement document is read.
+func ReadCAMT053(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	decoder := xml.NewDecoder(file)
+	// The input is transcoded to UTF-8 before parsing, whatever it declares.
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var transactions []transaction.Transaction
+	var rowErrors []RowError
+
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading camt.053 file: %v", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "Ntry" {
+			continue
+		}
+
+		line, _ := decoder.InputPos()
+		var entry camtEntry
+		if err := decoder.DecodeElement(&entry, &start); err != nil {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading camt.053 entry at line %d: %v", opts.LineOffset+line, err)
+		}
+
+		tx, problems := camtToTransaction(entry, opts.LineOffset+line)
+		if len(problems) == 0 {
+			tx.Source = opts.source(opts.LineOffset+line, offset)
+			transactions = append(transactions, tx)
+			continue
+		}
+		if err := collect(&rowErrors, problems, opts); err != nil {
+			return []transaction.Transaction{}, rowErrors, err
+		}
+	}
+
+	return transactions, rowErrors, nil
+}
+
+// camtToTransaction validates an Ntry and converts it into a Transaction.
+func camtToTransaction(entry camtEntry, line int) (transaction.Transaction, []RowError) {
+	var problems []RowError
+
+	// The booking date is either a date or a date time.
+	dateStr := strings.TrimSpace(entry.BookingDate)
+	column := "BookgDt/Dt"
+	if dateStr == "" && len(strings.TrimSpace(entry.BookingTime)) >= 10 {
+		dateStr = strings.TrimSpace(entry.BookingTime)[:10]
+		column = "BookgDt/DtTm"
+	}
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		problems = append(problems, RowError{Line: line, Column: column, Value: dateStr, Reason: fmt.Sprintf("invalid date format: %v", err)})
+	}
+
+	amount, err := parseDecimalAmount(entry.Amount)
+	if err != nil {
+		problems = append(problems, RowError{Line: line, Column: "Amt", Value: entry.Amount, Reason: fmt.Sprintf("invalid amount: %v", err)})
+	}
+
+	// Amounts are unsigned, the indicator tells credits from debits.
+	switch strings.TrimSpace(entry.CreditDebit) {
+	case "CRDT":
+	case "DBIT":
+		amount = -amount
+	default:
+		problems = append(problems, RowError{Line: line, Column: "CdtDbtInd", Value: entry.CreditDebit, Reason: "expected CRDT or DBIT"})
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	content := strings.TrimSpace(strings.Join(entry.Remittance, " "))
+	if content == "" {
+		content = strings.TrimSpace(entry.AdditionalInfo)
+	}
+
+	return transaction.Transaction{
+		Date:    date.Format("2006/01/02"),
+		Amount:  amount,
+		Content: content,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Reads entries from every statement of a camt.053 document
+func TestReadCAMT053MultiStatement(t *testing.T) {
+	camtContent := `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="VND">150000.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><Dt>2023-01-15</Dt></BookgDt>
+        <NtryDtls><TxDtls><RmtInf><Ustrd>Electricity</Ustrd><Ustrd>January</Ustrd></RmtInf></TxDtls></NtryDtls>
+      </Ntry>
+    </Stmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="VND">2000000</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><DtTm>2023-02-01T09:30:00</DtTm></BookgDt>
+        <AddtlNtryInf>Salary</AddtlNtryInf>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+	transactions, _, err := ReadCAMT053(strings.NewReader(camtContent), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/01/15", Amount: -150000, Content: "Electricity January"},
+		{Date: "2023/02/01", Amount: 2000000, Content: "Salary"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Rejects entries without a credit/debit indicator
+func TestReadCAMT053MissingIndicator(t *testing.T) {
+	camtContent := `<Document><BkToCstmrStmt><Stmt>
+<Ntry><Amt>1.00</Amt><BookgDt><Dt>2023-01-15</Dt></BookgDt></Ntry>
+</Stmt></BkToCstmrStmt></Document>`
+
+	_, rowErrors, err := ReadCAMT053(strings.NewReader(camtContent), Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Column != "CdtDbtInd" || rowErrors[0].Line != 2 {
+		t.Errorf("expected a CdtDbtInd error at line 2, got %v", rowErrors)
+	}
+}
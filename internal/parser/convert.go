@@ -3,7 +3,10 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
 // ParseYearMonth validates and parses the YYYYMM input.
@@ -28,3 +31,134 @@ func ParseYearMonth(yearMonth string) (int, time.Month, error) {
 
 	return year, time.Month(monthInt), nil
 }
+
+// PeriodAll is the period covering all transactions.
+const PeriodAll = "all"
+
+// ParsePeriod parses a period given as a month (YYYYMM or YYYY-MM), a year
+// (YYYY), a quarter (2023Q2), an ISO week (2023W14), a day (YYYY-MM-DD), an
+// inclusive range of days (2023-03-15..2023-04-14), where either end of the
+// range may be left open, or PeriodAll. Months, quarters and years begin
+// where the calendar has them begin.
+func ParsePeriod(period string, calendar transaction.Calendar) (transaction.Period, error) {
+	period = strings.ToUpper(strings.TrimSpace(period))
+	if period == strings.ToUpper(PeriodAll) {
+		return transaction.Period{Label: PeriodAll}, nil
+	}
+
+	if from, to, ok := strings.Cut(period, ".."); ok {
+		return DateRange(from, to)
+	}
+
+	switch {
+	case len(period) == 6 && !strings.ContainsAny(period, "QW-"):
+		year, month, err := ParseYearMonth(period)
+		if err != nil {
+			return transaction.Period{}, err
+		}
+		return calendar.Month(year, month), nil
+	case len(period) == 7 && period[4] == '-':
+		year, month, err := ParseYearMonth(period[:4] + period[5:])
+		if err != nil {
+			return transaction.Period{}, err
+		}
+		return calendar.Month(year, month), nil
+	case len(period) == 4:
+		year, err := parseYear(period)
+		if err != nil {
+			return transaction.Period{}, err
+		}
+		return calendar.Year(year), nil
+	case len(period) == 6 && period[4] == 'Q':
+		year, err := parseYear(period[:4])
+		if err != nil {
+			return transaction.Period{}, err
+		}
+		quarter := int(period[5] - '0')
+		if quarter < 1 || quarter > 4 {
+			return transaction.Period{}, fmt.Errorf("quarter must be between 1 and 4. Got: %s", period[5:])
+		}
+		return calendar.Quarter(year, quarter), nil
+	case (len(period) == 7 || len(period) == 6) && period[4] == 'W':
+		year, err := parseYear(period[:4])
+		if err != nil {
+			return transaction.Period{}, err
+		}
+		week, err := strconv.Atoi(period[5:])
+		if err != nil {
+			return transaction.Period{}, fmt.Errorf("invalid week in period: %v", err)
+		}
+		// Week 1 is the week with the year's first Thursday, so it holds 4 January.
+		jan4 := date(year, time.January, 4)
+		from := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7+7*(week-1))
+		if _, lastWeek := date(year, time.December, 28).ISOWeek(); week < 1 || week > lastWeek {
+			return transaction.Period{}, fmt.Errorf("week must be between 01 and %02d. Got: %02d", lastWeek, week)
+		}
+		return transaction.Period{
+			Label: fmt.Sprintf("%04d/W%02d", year, week),
+			From:  from,
+			To:    from.AddDate(0, 0, 6),
+		}, nil
+	case len(period) == 10:
+		day, err := ParseDate(period)
+		if err != nil {
+			return transaction.Period{}, err
+		}
+		return transaction.Period{Label: day.Format("2006/01/02"), From: day, To: day}, nil
+	}
+
+	return transaction.Period{}, fmt.Errorf("invalid period format. Expected YYYYMM, YYYY, YYYYQn, YYYYWnn or YYYY-MM-DD..YYYY-MM-DD, got: %s", period)
+}
+
+// DateRange returns the period between two inclusive days given as
+// YYYY-MM-DD. An empty end leaves the range open on that side.
+func DateRange(from string, to string) (transaction.Period, error) {
+	var period transaction.Period
+	var err error
+	if from = strings.TrimSpace(from); from != "" {
+		if period.From, err = ParseDate(from); err != nil {
+			return transaction.Period{}, err
+		}
+	}
+	if to = strings.TrimSpace(to); to != "" {
+		if period.To, err = ParseDate(to); err != nil {
+			return transaction.Period{}, err
+		}
+	}
+	if period.From.IsZero() && period.To.IsZero() {
+		return transaction.Period{}, fmt.Errorf("date range needs a start or an end")
+	}
+	if !period.From.IsZero() && !period.To.IsZero() && period.To.Before(period.From) {
+		return transaction.Period{}, fmt.Errorf("date range ends before it starts: %s..%s", from, to)
+	}
+
+	period.Label = ".."
+	if !period.From.IsZero() {
+		period.Label = period.From.Format("2006/01/02") + period.Label
+	}
+	if !period.To.IsZero() {
+		period.Label += period.To.Format("2006/01/02")
+	}
+	return period, nil
+}
+
+// ParseDate parses a day given as YYYY-MM-DD or YYYY/MM/DD.
+func ParseDate(s string) (time.Time, error) {
+	day, err := time.Parse("2006-01-02", strings.ReplaceAll(strings.TrimSpace(s), "/", "-"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q. Expected YYYY-MM-DD", s)
+	}
+	return day, nil
+}
+
+func parseYear(s string) (int, error) {
+	year, err := strconv.Atoi(s)
+	if err != nil || year < 1 {
+		return 0, fmt.Errorf("invalid year in period: %s", s)
+	}
+	return year, nil
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
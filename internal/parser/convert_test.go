@@ -3,6 +3,8 @@ package parser
 import (
 	"testing"
 	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
 // Correctly parses a valid YYYYMM string into year and month
@@ -38,3 +40,67 @@ func TestParseYearMonthInvalidLength(t *testing.T) {
 		}
 	}
 }
+
+// Parses months, years, quarters, ISO weeks, days and ranges into intervals
+func TestParsePeriod(t *testing.T) {
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		input    string
+		label    string
+		from, to time.Time
+	}{
+		{"202302", "2023/02", day(2023, time.February, 1), day(2023, time.February, 28)},
+		{"2024-02", "2024/02", day(2024, time.February, 1), day(2024, time.February, 29)},
+		{"2023", "2023", day(2023, time.January, 1), day(2023, time.December, 31)},
+		{"2023q2", "2023/Q2", day(2023, time.April, 1), day(2023, time.June, 30)},
+		{"2023W14", "2023/W14", day(2023, time.April, 3), day(2023, time.April, 9)},
+		{"2021W01", "2021/W01", day(2021, time.January, 4), day(2021, time.January, 10)},
+		{"2020W53", "2020/W53", day(2020, time.December, 28), day(2021, time.January, 3)},
+		{"2023-03-15", "2023/03/15", day(2023, time.March, 15), day(2023, time.March, 15)},
+		{"2023-03-15..2023-04-14", "2023/03/15..2023/04/14", day(2023, time.March, 15), day(2023, time.April, 14)},
+		{"..2023/04/14", "..2023/04/14", time.Time{}, day(2023, time.April, 14)},
+		{"ALL", "all", time.Time{}, time.Time{}},
+	}
+	for _, test := range tests {
+		period, err := ParsePeriod(test.input, transaction.Calendar{})
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.input, err)
+			continue
+		}
+		if period.Label != test.label || !period.From.Equal(test.from) || !period.To.Equal(test.to) {
+			t.Errorf("%s: expected %s %v..%v, got %s %v..%v", test.input, test.label, test.from, test.to, period.Label, period.From, period.To)
+		}
+	}
+}
+
+// Shifts months, quarters and years to a fiscal calendar, but not weeks
+func TestParsePeriodFiscal(t *testing.T) {
+	calendar := transaction.Calendar{MonthStartDay: 25, YearStartMonth: time.April}
+	tests := map[string]string{
+		"202302":  "2023/02 2023-02-25..2023-03-24",
+		"2023":    "FY2023 2023-04-25..2024-04-24",
+		"2023Q1":  "FY2023/Q1 2023-04-25..2023-07-24",
+		"2023W14": "2023/W14 2023-04-03..2023-04-09",
+	}
+	for input, expected := range tests {
+		period, err := ParsePeriod(input, calendar)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", input, err)
+			continue
+		}
+		if got := period.Label + " " + period.From.Format("2006-01-02") + ".." + period.To.Format("2006-01-02"); got != expected {
+			t.Errorf("%s: expected %s, got %s", input, expected, got)
+		}
+	}
+}
+
+// Rejects malformed periods and ranges
+func TestParsePeriodInvalid(t *testing.T) {
+	for _, input := range []string{"", "202313", "2023Q5", "2023W54", "2021W53", "2023-04-14..2023-03-15", "..", "2023-02-30", "last month"} {
+		if _, err := ParsePeriod(input, transaction.Calendar{}); err == nil {
+			t.Errorf("expected error for input %q, got nil", input)
+		}
+	}
+}
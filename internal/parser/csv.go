@@ -2,8 +2,10 @@ package parser
 
 import (
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -11,55 +13,395 @@ import (
 	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
+// Options configures how CSV records are converted into Transactions.
+type Options struct {
+	// Lenient skips invalid records and collects their problems instead of
+	// aborting on the first one.
+	Lenient bool
+	// MaxErrors makes a lenient parse give up once more problems than this
+	// have been collected. Zero means no limit.
+	MaxErrors int
+	// LineOffset is the number of input lines preceding the reader, so that
+	// reported line numbers match the original file.
+	LineOffset int
+	// Rejects, when set, receives a copy of every rejected record.
+	Rejects *RejectWriter
+	// Sheet selects the worksheet of a spreadsheet by name or 1-based position.
+	Sheet string
+	// Provenance records the line and byte offset of the input record on
+	// every transaction.
+	Provenance bool
+	// ByteOffset is the position of the reader in the original file, added
+	// to the offsets recorded for provenance.
+	ByteOffset int64
+	// Transcoded input is read from another encoding than UTF-8, so that
+	// its offsets are not positions in the original file and are left out
+	// of provenance.
+	Transcoded bool
+	// Columns names the field held by each CSV column, SkipColumn for the
+	// ones to ignore. Other names are kept as metadata of the transaction.
+	// Defaults to the expected headers in order.
+	Columns []string
+	// Comma is the field delimiter of CSV records, ',' when zero.
+	Comma rune
+	// DateLayout is the time layout of dates in the input, "2006/01/02"
+	// when empty. Dates are always output as YYYY/MM/DD.
+	DateLayout string
+	// ThousandsSeparator is removed from amounts.
+	ThousandsSeparator string
+	// DecimalSeparator allows amounts written with a zero fraction, such as
+	// "1.500,00". Amounts must be integers when it is empty.
+	DecimalSeparator string
+	// InvertSign negates amounts, for exports listing expenses as positive.
+	InvertSign bool
+}
+
+// SkipColumn marks a column that Options.Columns does not map to a field.
+const SkipColumn = "skip"
+
+// DebitColumn and CreditColumn are fields that Options.Columns can map
+// instead of the amount, for exports with separate columns for money going
+// out and coming in.
+const (
+	DebitColumn  = "debit"
+	CreditColumn = "credit"
+)
+
+// RowError describes a problem found in a single CSV record.
+type RowError struct {
+	Line   int    `json:"line"`
+	Column string `json:"column,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("%s at line %d", e.Reason, e.Line)
+	}
+	return fmt.Sprintf("%s in column '%s' at line %d", e.Reason, e.Column, e.Line)
+}
+
+// ErrTooManyErrors is returned by a lenient parse that exceeded Options.MaxErrors.
+var ErrTooManyErrors = errors.New("too many invalid records")
+
 // CSVtoTransactions reads and parses the CSV file into a slice of Transactions.
 func CSVtoTransactions(file io.Reader, expectedHeaders []string) ([]transaction.Transaction, error) {
+	transactions, _, err := ReadTransactions(file, expectedHeaders, Options{LineOffset: 1})
+	return transactions, err
+}
+
+// ReadTransactions reads and parses the CSV file into a slice of Transactions.
+// In lenient mode invalid records are skipped and returned as RowErrors.
+func ReadTransactions(file io.Reader, expectedHeaders []string, opts Options) ([]transaction.Transaction, []RowError, error) {
+	columns := opts.Columns
+	if columns == nil {
+		columns = expectedHeaders
+	}
+
+	reader, raw := newRecordReader(file, opts)
+	reader.FieldsPerRecord = len(columns)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
 
+	return readRecords(reader, raw, opts, func(record []string, line int) (transaction.Transaction, []RowError) {
+		mapped, problems := mapColumns(record, columns, expectedHeaders, line)
+		if len(problems) > 0 {
+			return transaction.Transaction{}, problems
+		}
+		tx, problems := recordToTransaction(mapped, expectedHeaders, line, opts)
+		if len(problems) == 0 {
+			tx.Metadata = recordMetadata(record, columns, expectedHeaders)
+		}
+		return tx, problems
+	})
+}
+
+// newRecordReader returns a CSV reader of file and, when rejected records
+// are written out, the recorder keeping their raw bytes.
+func newRecordReader(file io.Reader, opts Options) (*csv.Reader, *recorder) {
+	var raw *recorder
+	if opts.Rejects != nil {
+		raw = &recorder{r: file}
+		file = raw
+	}
 	reader := csv.NewReader(file)
 	reader.TrimLeadingSpace = true
+	return reader, raw
+}
+
+// readRecords converts the remaining records of the reader into
+// Transactions with convert, which is given the record and its line in the
+// file. Records that cannot be read or converted are written to the
+// rejects file, if any, and handled by collect.
+func readRecords(reader *csv.Reader, raw *recorder, opts Options, convert func(record []string, line int) (transaction.Transaction, []RowError)) ([]transaction.Transaction, []RowError, error) {
+	if raw != nil {
+		raw.discard(reader.InputOffset())
+	}
 
 	var transactions []transaction.Transaction
+	var rowErrors []RowError
 
 	// Read each record.
-	recordNumber := 1 // Including header
 	for {
+		start := reader.InputOffset()
 		record, err := reader.Read()
-		recordNumber++
+		if err == io.EOF {
+			break
+		}
+
+		var problems []RowError
 		if err != nil {
-			if err.Error() == "EOF" {
-				break
+			line := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+				err = parseErr.Err
+			}
+			problems = []RowError{{
+				Line:   opts.LineOffset + line,
+				Reason: fmt.Sprintf("error reading CSV record: %v", err),
+			}}
+		} else {
+			line, _ := reader.FieldPos(0)
+			var tx transaction.Transaction
+			tx, problems = convert(record, opts.LineOffset+line)
+			if len(problems) == 0 {
+				tx.Source = opts.source(opts.LineOffset+line, start)
+				transactions = append(transactions, tx)
+				if raw != nil {
+					raw.discard(reader.InputOffset())
+				}
+				continue
 			}
-			return []transaction.Transaction{}, fmt.Errorf("error reading CSV record at line %d: %v", recordNumber, err)
 		}
 
-		// Validate that no columns are empty.
-		for i, field := range record {
-			if strings.TrimSpace(field) == "" {
-				return []transaction.Transaction{}, fmt.Errorf("empty field in column '%s' at line %d", expectedHeaders[i], recordNumber)
+		if raw != nil {
+			end := reader.InputOffset()
+			if err := opts.Rejects.Write(raw.slice(start, end), rejectReason(problems)); err != nil {
+				return []transaction.Transaction{}, rowErrors, fmt.Errorf("error writing rejected record: %v", err)
 			}
+			raw.discard(end)
 		}
 
-		// Parse the date to ensure correct format.
-		dateStr := record[0]
-		if _, err := time.Parse("2006/01/02", dateStr); err != nil {
-			return []transaction.Transaction{}, fmt.Errorf("invalid date format at line %d: %v", recordNumber, err)
+		if err := collect(&rowErrors, problems, opts); err != nil {
+			return []transaction.Transaction{}, rowErrors, err
 		}
+	}
 
-		// Parse the amount.
-		amount, err := strconv.Atoi(record[1])
-		if err != nil {
-			return []transaction.Transaction{}, fmt.Errorf("invalid amount at line %d: %v", recordNumber, err)
+	return transactions, rowErrors, nil
+}
+
+// source returns the provenance of the record at line and input offset, or
+// nil unless Options.Provenance is set. The file is filled in by the caller.
+func (opts Options) source(line int, offset int64) *transaction.Source {
+	if !opts.Provenance {
+		return nil
+	}
+	source := &transaction.Source{Line: line}
+	if !opts.Transcoded {
+		offset += opts.ByteOffset
+		source.Offset = &offset
+	}
+	return source
+}
+
+// collect handles the problems of a rejected record: outside lenient mode
+// the first one is returned as error, otherwise they are appended to
+// rowErrors until Options.MaxErrors is exceeded.
+func collect(rowErrors *[]RowError, problems []RowError, opts Options) error {
+	if !opts.Lenient {
+		return problems[0]
+	}
+	*rowErrors = append(*rowErrors, problems...)
+	if opts.MaxErrors > 0 && len(*rowErrors) > opts.MaxErrors {
+		return fmt.Errorf("%w: more than %d problems found", ErrTooManyErrors, opts.MaxErrors)
+	}
+	return nil
+}
+
+// MissingColumn returns the first of expectedHeaders that columns do not
+// name, or an empty string if there is none. Debit and credit columns stand
+// in for the amount.
+func MissingColumn(columns []string, expectedHeaders []string) string {
+	for _, expected := range expectedHeaders {
+		if slices.Contains(columns, expected) {
+			continue
+		}
+		if expected == "amount" && (slices.Contains(columns, DebitColumn) || slices.Contains(columns, CreditColumn)) {
+			continue
+		}
+		return expected
+	}
+	return ""
+}
+
+// recordToTransaction validates a record and converts it into a Transaction.
+// In lenient mode all problems are reported, otherwise it stops at the
+// first one.
+func recordToTransaction(record []string, expectedHeaders []string, line int, opts Options) (transaction.Transaction, []RowError) {
+	var problems []RowError
+	report := func(column int, reason string) bool {
+		problems = append(problems, RowError{
+			Line:   line,
+			Column: columnName(expectedHeaders, column),
+			Value:  record[column],
+			Reason: reason,
+		})
+		return opts.Lenient
+	}
+
+	// Validate that no columns are empty.
+	for i, field := range record {
+		if strings.TrimSpace(field) == "" && !report(i, "empty field") {
+			return transaction.Transaction{}, problems
+		}
+	}
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	// Parse the date to ensure correct format.
+	dateStr, err := opts.parseDate(record[0])
+	if err != nil && !report(0, fmt.Sprintf("invalid date format: %v", err)) {
+		return transaction.Transaction{}, problems
+	}
+
+	// Parse the amount.
+	amount, err := opts.parseAmount(record[1])
+	if err != nil && !report(1, fmt.Sprintf("invalid amount: %v", err)) {
+		return transaction.Transaction{}, problems
+	}
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	return transaction.Transaction{
+		Date:    dateStr,
+		Amount:  amount,
+		Content: record[2],
+	}, nil
+}
+
+// parseDate parses a date laid out as Options.DateLayout and returns it in
+// the YYYY/MM/DD layout of the output.
+func (opts Options) parseDate(s string) (string, error) {
+	if opts.DateLayout == "" {
+		_, err := time.Parse("2006/01/02", s)
+		return s, err
+	}
+	date, err := time.Parse(opts.DateLayout, strings.TrimSpace(s))
+	if err != nil {
+		return "", err
+	}
+	return date.Format("2006/01/02"), nil
+}
+
+// parseAmount parses an amount using the separators of the options.
+func (opts Options) parseAmount(s string) (int, error) {
+	if opts.ThousandsSeparator != "" {
+		s = strings.ReplaceAll(s, opts.ThousandsSeparator, "")
+	}
+
+	var amount int
+	var err error
+	if opts.DecimalSeparator == "" {
+		amount, err = strconv.Atoi(strings.TrimSpace(s))
+	} else {
+		amount, err = parseDecimalAmount(strings.Replace(s, opts.DecimalSeparator, ".", 1))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if opts.InvertSign {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// mapColumns reorders the fields of a record laid out as columns into the
+// order of expectedHeaders, dropping skipped columns. Debit and credit
+// columns are combined into the amount, which is a RowError when both hold
+// a value other than zero.
+func mapColumns(record []string, columns []string, expectedHeaders []string, line int) ([]string, []RowError) {
+	if slices.Equal(columns, expectedHeaders) {
+		return record, nil
+	}
+	mapped := make([]string, len(expectedHeaders))
+	var debit, credit string
+	for i, column := range columns {
+		switch column {
+		case DebitColumn:
+			debit = strings.TrimSpace(record[i])
+		case CreditColumn:
+			credit = strings.TrimSpace(record[i])
+		default:
+			if j := slices.Index(expectedHeaders, column); j >= 0 {
+				mapped[j] = record[i]
+			}
 		}
+	}
+	if j := slices.Index(expectedHeaders, "amount"); j >= 0 && mapped[j] == "" {
+		amount, ok := signedAmount(debit, credit)
+		if !ok {
+			return nil, []RowError{{Line: line, Column: "amount", Reason: "both debit and credit set"}}
+		}
+		mapped[j] = amount
+	}
+	return mapped, nil
+}
 
-		content := record[2]
+// signedAmount returns the amount of a record with separate debit and
+// credit columns, or false when both hold a value other than zero.
+func signedAmount(debit string, credit string) (string, bool) {
+	isZero := func(s string) bool {
+		return strings.Trim(s, "0.,") == ""
+	}
+	switch {
+	case isZero(debit):
+		return credit, true
+	case isZero(credit):
+		return "-" + strings.TrimPrefix(debit, "-"), true
+	}
+	return "", false
+}
 
-		transaction := transaction.Transaction{
-			Date:    dateStr,
-			Amount:  amount,
-			Content: content,
+// recordMetadata returns the non-empty values of the columns that are
+// neither expected headers nor skipped, by column name.
+func recordMetadata(record []string, columns []string, expectedHeaders []string) map[string]string {
+	var metadata map[string]string
+	for i, column := range columns {
+		if column == SkipColumn || column == DebitColumn || column == CreditColumn || slices.Contains(expectedHeaders, column) || strings.TrimSpace(record[i]) == "" {
+			continue
+		}
+		if metadata == nil {
+			metadata = map[string]string{}
 		}
+		metadata[transaction.MetadataKey(column)] = record[i]
+	}
+	return metadata
+}
 
-		transactions = append(transactions, transaction)
+// columnName returns the expected header of a column, falling back to its
+// position for columns beyond the expected ones.
+func columnName(expectedHeaders []string, i int) string {
+	if i < len(expectedHeaders) {
+		return expectedHeaders[i]
 	}
+	return fmt.Sprintf("column %d", i+1)
+}
 
-	return transactions, nil
+// rejectReason joins the problems of a record into a single reason.
+func rejectReason(problems []RowError) string {
+	reasons := make([]string, len(problems))
+	for i, p := range problems {
+		if p.Column == "" {
+			reasons[i] = p.Reason
+		} else {
+			reasons[i] = fmt.Sprintf("%s: %s", p.Column, p.Reason)
+		}
+	}
+	return strings.Join(reasons, "; ")
 }
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -30,3 +31,167 @@ func TestCSVtoTransactionsSuccess(t *testing.T) {
 		t.Errorf("expected %v, got %v", expectedTransactions, transactions)
 	}
 }
+
+// Skips invalid records in lenient mode and reports every problem found
+func TestReadTransactionsLenient(t *testing.T) {
+	csvContent := "2023/10/01,100,Groceries\n" +
+		"2023-10-02,abc,Rent\n" +
+		"2023/10/03,,Salary\n" +
+		"2023/10/04,-50,Coffee\n"
+
+	transactions, rowErrors, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, Options{Lenient: true, LineOffset: 1})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expectedTransactions := []transaction.Transaction{
+		{Date: "2023/10/01", Amount: 100, Content: "Groceries"},
+		{Date: "2023/10/04", Amount: -50, Content: "Coffee"},
+	}
+	if !reflect.DeepEqual(transactions, expectedTransactions) {
+		t.Errorf("expected %v, got %v", expectedTransactions, transactions)
+	}
+
+	expectedErrors := []struct {
+		line   int
+		column string
+		value  string
+	}{
+		{3, "date", "2023-10-02"},
+		{3, "amount", "abc"},
+		{4, "amount", ""},
+	}
+	if len(rowErrors) != len(expectedErrors) {
+		t.Fatalf("expected %d errors, got %v", len(expectedErrors), rowErrors)
+	}
+	for i, expected := range expectedErrors {
+		got := rowErrors[i]
+		if got.Line != expected.line || got.Column != expected.column || got.Value != expected.value {
+			t.Errorf("error %d: expected line %d column %q value %q, got %+v", i, expected.line, expected.column, expected.value, got)
+		}
+	}
+}
+
+// Gives up once more problems than MaxErrors were found
+func TestReadTransactionsMaxErrors(t *testing.T) {
+	csvContent := "bad,1,a\nbad,2,b\n2023/10/01,3,c\n"
+
+	_, _, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, Options{Lenient: true, MaxErrors: 1})
+	if !errors.Is(err, ErrTooManyErrors) {
+		t.Errorf("expected ErrTooManyErrors, got %v", err)
+	}
+}
+
+// Maps records laid out by a column spec and ignores skipped columns
+func TestReadTransactionsColumns(t *testing.T) {
+	csvContent := "REF1,-100,2023/10/01,Groceries\nREF2,200,2023/10/02,Salary\n"
+
+	opts := Options{Columns: []string{"skip", "amount", "date", "content"}}
+	transactions, _, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/10/01", Amount: -100, Content: "Groceries"},
+		{Date: "2023/10/02", Amount: 200, Content: "Salary"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Keeps the values of extra columns as metadata
+func TestReadTransactionsMetadata(t *testing.T) {
+	csvContent := "2023/10/01,-100,Groceries,REF1,ATM\n2023/10/02,200,Salary,REF2,\n"
+
+	opts := Options{Columns: []string{"date", "amount", "content", "reference", "channel"}}
+	transactions, _, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/10/01", Amount: -100, Content: "Groceries", Metadata: map[string]string{"reference": "REF1", "channel": "ATM"}},
+		{Date: "2023/10/02", Amount: 200, Content: "Salary", Metadata: map[string]string{"reference": "REF2"}},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Leaves offsets out of the provenance of transcoded input
+func TestReadTransactionsProvenanceTranscoded(t *testing.T) {
+	csvContent := "2023/10/01,-100,Groceries\n"
+
+	opts := Options{Provenance: true, Transcoded: true}
+	transactions, _, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := (&transaction.Source{Line: 1}); !reflect.DeepEqual(transactions[0].Source, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions[0].Source)
+	}
+}
+
+func offset(n int64) *int64 {
+	return &n
+}
+
+// Records the line and byte offset of each record with provenance
+func TestReadTransactionsProvenance(t *testing.T) {
+	csvContent := "2023/10/01,-100,Groceries\n2023/10/02,200,Salary\n"
+
+	opts := Options{LineOffset: 1, Provenance: true, ByteOffset: 20}
+	transactions, _, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []*transaction.Source{{Line: 2, Offset: offset(20)}, {Line: 3, Offset: offset(46)}}
+	for i, tx := range transactions {
+		if !reflect.DeepEqual(tx.Source, expected[i]) {
+			t.Errorf("expected %v, got %v", expected[i], tx.Source)
+		}
+	}
+}
+
+// Reads records formatted like a bank export with debit and credit columns
+func TestReadTransactionsFormat(t *testing.T) {
+	csvContent := "05/10/2023;Salary;;1.500,00\n06/10/2023;Groceries;120,00;\n07/10/2023;Refund;10;5\n"
+
+	opts := Options{
+		Lenient:            true,
+		Columns:            []string{"date", "content", "debit", "credit"},
+		Comma:              ';',
+		DateLayout:         "02/01/2006",
+		ThousandsSeparator: ".",
+		DecimalSeparator:   ",",
+	}
+	transactions, rowErrors, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/10/05", Amount: 1500, Content: "Salary"},
+		{Date: "2023/10/06", Amount: -120, Content: "Groceries"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 3 || rowErrors[0].Column != "amount" || rowErrors[0].Reason != "both debit and credit set" {
+		t.Errorf("expected both debit and credit to be set at line 3, got %v", rowErrors)
+	}
+}
+
+// Negates amounts of exports listing expenses as positive
+func TestReadTransactionsInvertSign(t *testing.T) {
+	transactions, _, err := ReadTransactions(strings.NewReader("2023/10/01,100,Groceries\n"), []string{"date", "amount", "content"}, Options{InvertSign: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].Amount != -100 {
+		t.Errorf("expected an amount of -100, got %v", transactions)
+	}
+}
@@ -0,0 +1,247 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Supported input character encodings. Input in any of them is transcoded to
+// UTF-8 before parsing.
+const (
+	EncodingAuto        = "auto"
+	EncodingUTF8        = "utf-8"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingLatin1      = "iso-8859-1"
+	EncodingWindows1252 = "windows-1252"
+)
+
+var encodingAliases = map[string]string{
+	"":             EncodingAuto,
+	"auto":         EncodingAuto,
+	"utf-8":        EncodingUTF8,
+	"utf8":         EncodingUTF8,
+	"utf-16le":     EncodingUTF16LE,
+	"utf16le":      EncodingUTF16LE,
+	"utf-16be":     EncodingUTF16BE,
+	"utf16be":      EncodingUTF16BE,
+	"iso-8859-1":   EncodingLatin1,
+	"iso8859-1":    EncodingLatin1,
+	"latin1":       EncodingLatin1,
+	"latin-1":      EncodingLatin1,
+	"windows-1252": EncodingWindows1252,
+	"cp1252":       EncodingWindows1252,
+}
+
+// ParseEncoding returns the canonical name of an encoding or one of its
+// aliases such as "latin1" or "cp1252".
+func ParseEncoding(name string) (string, error) {
+	encoding, ok := encodingAliases[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return "", fmt.Errorf("unsupported encoding '%s'", name)
+	}
+	return encoding, nil
+}
+
+// DetectEncoding guesses the encoding from the start of the input: a byte
+// order mark wins, then NUL bytes in every other position point to UTF-16,
+// and input that is not valid UTF-8 is taken as Windows-1252.
+func DetectEncoding(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE
+	}
+
+	// Mostly ASCII text in UTF-16 has a NUL byte in every code unit.
+	var evenNULs, oddNULs int
+	for i, b := range head {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenNULs++
+		} else {
+			oddNULs++
+		}
+	}
+	units := len(head) / 2
+	switch {
+	case units > 0 && oddNULs > units/2 && oddNULs > evenNULs*4:
+		return EncodingUTF16LE
+	case units > 0 && evenNULs > units/2 && evenNULs > oddNULs*4:
+		return EncodingUTF16BE
+	}
+
+	// Ignore a rune cut off at the end of the sample.
+	valid := head
+	for i := 1; i <= utf8.UTFMax && i <= len(head); i++ {
+		if utf8.RuneStart(head[len(head)-i]) {
+			if !utf8.FullRune(head[len(head)-i:]) {
+				valid = head[:len(head)-i]
+			}
+			break
+		}
+	}
+	if utf8.Valid(valid) {
+		return EncodingUTF8
+	}
+	return EncodingWindows1252
+}
+
+// Newline returns the bytes of a line feed in the encoding.
+func Newline(encoding string) []byte {
+	switch encoding {
+	case EncodingUTF16LE:
+		return []byte{'\n', 0}
+	case EncodingUTF16BE:
+		return []byte{0, '\n'}
+	}
+	return []byte{'\n'}
+}
+
+// BOM returns the byte order mark of the encoding, nil if it has none.
+func BOM(encoding string) []byte {
+	switch encoding {
+	case EncodingUTF8:
+		return []byte{0xEF, 0xBB, 0xBF}
+	case EncodingUTF16LE:
+		return []byte{0xFF, 0xFE}
+	case EncodingUTF16BE:
+		return []byte{0xFE, 0xFF}
+	}
+	return nil
+}
+
+// DecodeReader returns a reader transcoding r from the encoding to UTF-8.
+// A byte order mark at the start of r is dropped.
+func DecodeReader(r io.Reader, encoding string) io.Reader {
+	br := bufio.NewReader(r)
+	if bom := BOM(encoding); bom != nil {
+		if head, _ := br.Peek(len(bom)); bytes.Equal(head, bom) {
+			br.Discard(len(bom))
+		}
+	}
+	switch encoding {
+	case EncodingUTF16LE, EncodingUTF16BE:
+		return &utf16Reader{r: br, bigEndian: encoding == EncodingUTF16BE}
+	case EncodingLatin1:
+		return &singleByteReader{r: br}
+	case EncodingWindows1252:
+		return &singleByteReader{r: br, table: &windows1252}
+	}
+	return br
+}
+
+// utf16Reader transcodes UTF-16 to UTF-8.
+type utf16Reader struct {
+	r         *bufio.Reader
+	bigEndian bool
+	pending   []byte // encoded output not yet returned
+	err       error
+}
+
+func (u *utf16Reader) Read(p []byte) (int, error) {
+	for len(u.pending) == 0 {
+		if u.err != nil {
+			return 0, u.err
+		}
+		u.fill(len(p))
+	}
+	n := copy(p, u.pending)
+	u.pending = u.pending[n:]
+	return n, nil
+}
+
+// fill decodes about n bytes worth of code units into pending.
+func (u *utf16Reader) fill(n int) {
+	u.pending = u.pending[:0]
+	for len(u.pending) < n {
+		unit, err := u.unit()
+		if err != nil {
+			u.err = err
+			return
+		}
+		r := rune(unit)
+		if utf16.IsSurrogate(r) {
+			// A high surrogate is completed by a low one, which is only
+			// consumed then. Any other surrogate is invalid on its own.
+			high := r
+			r = utf8.RuneError
+			if high < 0xDC00 {
+				if b, err := u.r.Peek(2); err == nil {
+					if low := rune(u.decode(b)); low >= 0xDC00 && low <= 0xDFFF {
+						u.r.Discard(2)
+						r = utf16.DecodeRune(high, low)
+					}
+				}
+			}
+		}
+		u.pending = utf8.AppendRune(u.pending, r)
+	}
+}
+
+func (u *utf16Reader) unit() (uint16, error) {
+	var b [2]byte
+	if _, err := io.ReadFull(u.r, b[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return 0, err
+	}
+	return u.decode(b[:]), nil
+}
+
+// decode returns the code unit encoded by the first two bytes of b.
+func (u *utf16Reader) decode(b []byte) uint16 {
+	if u.bigEndian {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return uint16(b[1])<<8 | uint16(b[0])
+}
+
+// singleByteReader transcodes a single-byte encoding to UTF-8. Without a
+// table bytes are Latin-1 code points; the table overrides 0x80 to 0x9F.
+type singleByteReader struct {
+	r       *bufio.Reader
+	table   *[32]rune
+	buf     []byte
+	pending []byte
+}
+
+func (s *singleByteReader) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 {
+		// Every byte becomes at most 3 bytes of UTF-8.
+		if want := max(len(p)/3, 1); cap(s.buf) < want {
+			s.buf = make([]byte, want)
+		}
+		n, err := s.r.Read(s.buf[:max(len(p)/3, 1)])
+		for _, b := range s.buf[:n] {
+			r := rune(b)
+			if s.table != nil && b >= 0x80 && b <= 0x9F {
+				r = s.table[b-0x80]
+			}
+			s.pending = utf8.AppendRune(s.pending, r)
+		}
+		if n == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
+
+// windows1252 maps 0x80 to 0x9F, where Windows-1252 differs from Latin-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// Detects encodings from byte order marks and content
+func TestDetectEncoding(t *testing.T) {
+	cases := []struct {
+		name     string
+		head     []byte
+		expected string
+	}{
+		{"UTF-8 BOM", []byte("\xEF\xBB\xBFdate,amount"), EncodingUTF8},
+		{"UTF-16LE BOM", []byte("\xFF\xFEd\x00a\x00"), EncodingUTF16LE},
+		{"UTF-16BE BOM", []byte("\xFE\xFF\x00d\x00a"), EncodingUTF16BE},
+		{"UTF-16LE without BOM", []byte("d\x00a\x00t\x00e\x00,\x00"), EncodingUTF16LE},
+		{"UTF-16BE without BOM", []byte("\x00d\x00a\x00t\x00e\x00,"), EncodingUTF16BE},
+		{"UTF-8", []byte("2023/01/01,-1,Ăn trưa"), EncodingUTF8},
+		{"UTF-8 cut in a rune", []byte("2023/01/01,-1,\xC4"), EncodingUTF8},
+		{"Windows-1252", []byte("2023/01/01,-1,caf\xE9 \x93quoted\x94"), EncodingWindows1252},
+	}
+
+	for _, c := range cases {
+		if got := DetectEncoding(c.head); got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, got)
+		}
+	}
+}
+
+// Transcodes input to UTF-8 and drops byte order marks
+func TestDecodeReader(t *testing.T) {
+	cases := []struct {
+		encoding string
+		input    []byte
+		expected string
+	}{
+		{EncodingUTF8, []byte("\xEF\xBB\xBFĂn trưa"), "Ăn trưa"},
+		{EncodingUTF16LE, []byte("\xFF\xFE\x02\x01n\x00 \x00=\xD8\x00\xDE"), "Ăn 😀"},
+		{EncodingUTF16BE, []byte("\xFE\xFF\x01\x02\x00n"), "Ăn"},
+		{EncodingLatin1, []byte("caf\xE9 \x80"), "café \u0080"},
+		{EncodingWindows1252, []byte("caf\xE9 \x80 \x93x\x94"), "café € “x”"},
+	}
+
+	for _, c := range cases {
+		got, err := io.ReadAll(DecodeReader(bytes.NewReader(c.input), c.encoding))
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", c.encoding, err)
+			continue
+		}
+		if string(got) != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.encoding, c.expected, string(got))
+		}
+	}
+}
+
+// Replaces unpaired UTF-16 surrogates without dropping the unit after them
+func TestDecodeReaderUnpairedSurrogates(t *testing.T) {
+	cases := []struct {
+		input    []byte
+		expected string
+	}{
+		{[]byte("\x00\xDCa\x00"), "\uFFFDa"},
+		{[]byte("=\xD8b\x00"), "\uFFFDb"},
+		{[]byte("=\xD8=\xD8\x00\xDE"), "\uFFFD😀"},
+		{[]byte("a\x00=\xD8"), "a\uFFFD"},
+	}
+
+	for _, c := range cases {
+		got, err := io.ReadAll(DecodeReader(bytes.NewReader(c.input), EncodingUTF16LE))
+		if err != nil {
+			t.Errorf("%q: expected no error, got %v", c.input, err)
+			continue
+		}
+		if string(got) != c.expected {
+			t.Errorf("%q: expected %q, got %q", c.input, c.expected, string(got))
+		}
+	}
+}
+
+// Accepts encoding aliases and rejects unknown encodings
+func TestParseEncoding(t *testing.T) {
+	if got, err := ParseEncoding("CP1252"); err != nil || got != EncodingWindows1252 {
+		t.Errorf("expected %s, got %s (%v)", EncodingWindows1252, got, err)
+	}
+	if _, err := ParseEncoding("ebcdic"); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
@@ -0,0 +1,233 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Importer reads the transactions of an input format.
+type Importer interface {
+	Read(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error)
+}
+
+// ImporterFunc adapts a read function to the Importer interface.
+type ImporterFunc func(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error)
+
+func (f ImporterFunc) Read(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	return f(file, opts)
+}
+
+// Layout tells how the records of a format are laid out in a file.
+type Layout int
+
+const (
+	// LayoutDocument formats are read as a whole.
+	LayoutDocument Layout = iota
+	// LayoutLines formats hold one record per line, so that files can be
+	// split into parts at line boundaries.
+	LayoutLines
+	// LayoutCSV formats are line formats starting with a header row, which
+	// is checked against the expected headers before the records are read.
+	LayoutCSV
+)
+
+// Format describes an input format and how to recognise its files.
+type Format struct {
+	// Name selects the format explicitly, as with -format.
+	Name string
+	// Extensions are the lowercase file extensions of the format.
+	Extensions []string
+	// Magic reports whether the start of a file is in the format. It tells
+	// apart formats sharing an extension, and recognises files whose
+	// extension is not registered.
+	Magic func(head []byte) bool
+	// Binary formats are not transcoded to UTF-8.
+	Binary bool
+	// Rejects tells that the importer writes rejected records to
+	// Options.Rejects. Other formats cannot reproduce their records as rows.
+	Rejects bool
+	Layout  Layout
+	// Convert, when set, renders binary input as CSV text, which is read
+	// like a CSV file by the Importer. It also returns the number of rows
+	// dropped before the text, so that line numbers count them.
+	Convert  func(file io.Reader, opts Options) ([]byte, int, error)
+	Importer Importer
+}
+
+// ExpectedHeaders are the fields of a transaction, in the column order of
+// the CSV files written by this tool.
+var ExpectedHeaders = []string{"date", "amount", "content"}
+
+// CSVFormat is the format of files that no other format recognises.
+var CSVFormat = Format{
+	Name:       "csv",
+	Extensions: []string{".csv"},
+	Rejects:    true,
+	Layout:     LayoutCSV,
+	Importer: ImporterFunc(func(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+		return ReadTransactions(file, ExpectedHeaders, opts)
+	}),
+}
+
+var formats = []Format{CSVFormat}
+
+func init() {
+	Register(Format{
+		Name:       "xlsx",
+		Extensions: []string{".xlsx"},
+		Magic:      hasPrefix("PK\x03\x04"),
+		Binary:     true,
+		Rejects:    true,
+		Layout:     LayoutCSV,
+		Convert:    XLSXToCSV,
+		Importer:   CSVFormat.Importer,
+	})
+	Register(Format{
+		Name:       "ofx",
+		Extensions: []string{".ofx", ".qfx"},
+		Magic:      containsAny("OFXHEADER:", "<OFX>", "<?OFX"),
+		Importer:   ImporterFunc(ReadOFX),
+	})
+	Register(Format{
+		Name:       "qif",
+		Extensions: []string{".qif"},
+		Magic:      hasPrefix("!Type:", "!Account", "!Option"),
+		Importer:   ImporterFunc(ReadQIF),
+	})
+	Register(Format{
+		Name:       "camt053",
+		Extensions: []string{".xml"},
+		Magic:      containsAny("camt.053"),
+		Importer:   ImporterFunc(ReadCAMT053),
+	})
+	Register(Format{
+		Name:       "mt940",
+		Extensions: []string{".sta", ".940"},
+		Magic:      hasPrefix("{1:", ":20:"),
+		Importer:   ImporterFunc(ReadMT940),
+	})
+	Register(Format{
+		Name:       "json",
+		Extensions: []string{".json"},
+		Magic:      hasPrefix("["),
+		Importer:   ImporterFunc(ReadJSON),
+	})
+	Register(Format{
+		Name:       "ndjson",
+		Extensions: []string{".ndjson", ".jsonl"},
+		Magic:      hasPrefix("{"),
+		Rejects:    true,
+		Layout:     LayoutLines,
+		Importer:   ImporterFunc(ReadNDJSON),
+	})
+}
+
+// Register adds a format to the registry. It panics if the name is already
+// taken. Formats sharing an extension with another one must have a Magic
+// function.
+func Register(format Format) {
+	for _, registered := range formats {
+		if registered.Name == format.Name {
+			panic(fmt.Sprintf("parser: format '%s' registered twice", format.Name))
+		}
+	}
+	formats = append(formats, format)
+}
+
+// FormatNames returns the names of the registered formats.
+func FormatNames() []string {
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		names[i] = format.Name
+	}
+	return names
+}
+
+// LookupFormat returns the registered format with the given name.
+func LookupFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, format := range formats {
+		if format.Name == name {
+			return format, nil
+		}
+	}
+	return Format{}, fmt.Errorf("unknown format '%s', expected one of %s", name, strings.Join(FormatNames(), ", "))
+}
+
+// DetectFormat picks the format of a file from its extension and the magic
+// bytes at the start of the file, which are also checked after transcoding
+// to UTF-8. Among the formats of the extension, the first one whose magic
+// matches wins, else the first one without magic. Files with an extension
+// that is not registered, or that no format of the extension recognises,
+// go by magic alone. Unrecognised files are read in the first format of
+// their extension, or as CSV.
+func DetectFormat(filePath string, head []byte) Format {
+	text := head
+	if encoding := DetectEncoding(head); encoding != EncodingUTF8 {
+		text, _ = io.ReadAll(DecodeReader(bytes.NewReader(head), encoding))
+	}
+	matches := func(format Format) bool {
+		return format.Magic != nil && (format.Magic(head) || (!format.Binary && format.Magic(text)))
+	}
+
+	ext := strings.ToLower(filepath.Ext(filePath))
+	var candidates []Format
+	for _, format := range formats {
+		if slices.Contains(format.Extensions, ext) {
+			candidates = append(candidates, format)
+		}
+	}
+
+	for _, format := range candidates {
+		if matches(format) {
+			return format
+		}
+	}
+	for _, format := range candidates {
+		if format.Magic == nil {
+			return format
+		}
+	}
+	for _, format := range formats {
+		if matches(format) {
+			return format
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return CSVFormat
+}
+
+// hasPrefix returns a Magic function matching input that starts with one
+// of the prefixes, after a byte order mark and white space.
+func hasPrefix(prefixes ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		head = bytes.TrimLeft(bytes.TrimPrefix(head, BOM(EncodingUTF8)), " \t\r\n")
+		for _, prefix := range prefixes {
+			if bytes.HasPrefix(head, []byte(prefix)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// containsAny returns a Magic function matching input that contains one of
+// the markers.
+func containsAny(markers ...string) func([]byte) bool {
+	return func(head []byte) bool {
+		for _, marker := range markers {
+			if bytes.Contains(head, []byte(marker)) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"testing"
+)
+
+// Picks formats by extension first, then by magic bytes, else CSV
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		path     string
+		head     string
+		expected string
+	}{
+		{"statement.QFX", "", "ofx"},
+		{"statement.csv", "[", "csv"},
+		{"statement.json", "{\"date\": \"2023/10/01\"}\n", "ndjson"},
+		{"statement.txt", ":20:STATEMENT\n:25:ACCOUNT\n", "mt940"},
+		{"statement.dat", "OFXHEADER:100\nDATA:OFXSGML\n", "ofx"},
+		{"export", "\xef\xbb\xbf!Type:Bank\n", "qif"},
+		{"export", "\xff\xfe{\x00\"\x00", "ndjson"},
+		{"export", "<?xml version=\"1.0\"?><Document xmlns=\"urn:iso:std:iso:20022:tech:xsd:camt.053.001.02\">", "camt053"},
+		{"export", "PK\x03\x04", "xlsx"},
+		{"export", "date,amount,content\n", "csv"},
+	}
+	for _, test := range tests {
+		if format := DetectFormat(test.path, []byte(test.head)); format.Name != test.expected {
+			t.Errorf("%s %q: expected %s, got %s", test.path, test.head, test.expected, format.Name)
+		}
+	}
+}
+
+// Looks formats up by name, rejecting unknown ones
+func TestLookupFormat(t *testing.T) {
+	format, err := LookupFormat(" NDJSON")
+	if err != nil || format.Name != "ndjson" || format.Layout != LayoutLines {
+		t.Errorf("expected the ndjson format, got %v, %v", format.Name, err)
+	}
+	if _, err := LookupFormat("pdf"); err == nil {
+		t.Errorf("expected an error for an unknown format, got nil")
+	}
+}
@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// ReadJSON reads a JSON array of transactions using the field names of the
+// Transaction JSON output. The array is decoded one element at a time.
+func ReadJSON(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	lines := &lineIndex{recorder: recorder{r: file}}
+	decoder := json.NewDecoder(lines)
+
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return []transaction.Transaction{}, nil, fmt.Errorf("error reading JSON file: expected an array of transactions")
+	}
+
+	var transactions []transaction.Transaction
+	var rowErrors []RowError
+
+	for decoder.More() {
+		elementLine, offset := lines.elementLine(decoder.InputOffset())
+		line := opts.LineOffset + elementLine
+
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading JSON file at line %d: %v", line, err)
+		}
+
+		tx, problems := jsonToTransaction(item, line)
+		if len(problems) == 0 {
+			tx.Source = opts.source(line, offset)
+			transactions = append(transactions, tx)
+			continue
+		}
+		if err := collect(&rowErrors, problems, opts); err != nil {
+			return []transaction.Transaction{}, rowErrors, err
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading JSON file: %v", err)
+	}
+
+	return transactions, rowErrors, nil
+}
+
+// ReadNDJSON reads newline-delimited JSON, one transaction object per line.
+// Blank lines are ignored. Rejected lines are written to the rejects file,
+// if any.
+func ReadNDJSON(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	reader := bufio.NewReader(file)
+
+	var transactions []transaction.Transaction
+	var rowErrors []RowError
+
+	line := opts.LineOffset
+	var offset int64
+	for {
+		start := offset
+		text, err := reader.ReadString('\n')
+		if text == "" && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading NDJSON file: %v", err)
+		}
+		line++
+		offset += int64(len(text))
+
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		tx, problems := jsonToTransaction([]byte(text), line)
+		if len(problems) == 0 {
+			tx.Source = opts.source(line, start)
+			transactions = append(transactions, tx)
+			continue
+		}
+		if opts.Rejects != nil {
+			if err := opts.Rejects.Write([]byte(text), rejectReason(problems)); err != nil {
+				return []transaction.Transaction{}, rowErrors, fmt.Errorf("error writing rejected record: %v", err)
+			}
+		}
+		if err := collect(&rowErrors, problems, opts); err != nil {
+			return []transaction.Transaction{}, rowErrors, err
+		}
+	}
+
+	return transactions, rowErrors, nil
+}
+
+// jsonToTransaction validates a JSON object and converts it into a
+// Transaction, applying the same rules as the CSV parser.
+func jsonToTransaction(data []byte, line int) (transaction.Transaction, []RowError) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return transaction.Transaction{}, []RowError{{Line: line, Reason: fmt.Sprintf("invalid JSON object: %v", err)}}
+	}
+
+	var problems []RowError
+	report := func(column string, value json.RawMessage, reason string) {
+		problems = append(problems, RowError{Line: line, Column: column, Value: string(value), Reason: reason})
+	}
+
+	var date, content, category string
+	if err := json.Unmarshal(fields["date"], &date); err != nil {
+		report("date", fields["date"], "expected a string")
+	} else if _, err := time.Parse("2006/01/02", date); err != nil {
+		report("date", fields["date"], fmt.Sprintf("invalid date format: %v", err))
+	}
+
+	var amountNumber json.Number
+	amount := 0
+	if err := json.Unmarshal(fields["amount"], &amountNumber); err != nil || amountNumber == "" {
+		report("amount", fields["amount"], "expected a number")
+	} else if amount, err = parseDecimalAmount(amountNumber.String()); err != nil {
+		report("amount", fields["amount"], fmt.Sprintf("invalid amount: %v", err))
+	}
+
+	if err := json.Unmarshal(fields["content"], &content); err != nil {
+		report("content", fields["content"], "expected a string")
+	} else if strings.TrimSpace(content) == "" {
+		report("content", fields["content"], "empty field")
+	}
+
+	if raw, ok := fields["category"]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &category); err != nil {
+			report("category", raw, "expected a string")
+		}
+	}
+
+	var metadata map[string]string
+	if raw, ok := fields["metadata"]; ok && string(raw) != "null" {
+		var values map[string]string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			report("metadata", raw, "expected an object of strings")
+		}
+		for key, value := range values {
+			if metadata == nil {
+				metadata = make(map[string]string, len(values))
+			}
+			key = transaction.MetadataKey(key)
+			if _, ok := metadata[key]; ok {
+				report("metadata", raw, fmt.Sprintf("duplicate key '%s'", key))
+				break
+			}
+			metadata[key] = value
+		}
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	return transaction.Transaction{
+		Date:     date,
+		Amount:   amount,
+		Content:  content,
+		Category: category,
+		Metadata: metadata,
+	}, nil
+}
+
+// lineIndex turns input offsets of the JSON decoder into line numbers.
+// Offsets must be looked up in increasing order.
+type lineIndex struct {
+	recorder
+	pos   int64
+	lines int
+}
+
+// elementLine returns the 1-based line and the input offset of the first
+// element at or after offset. The decoder stops before separators, so
+// whitespace and commas are skipped.
+func (li *lineIndex) elementLine(offset int64) (int, int64) {
+	data := li.slice(offset, li.base+int64(len(li.buf)))
+	target := offset + int64(len(data)-len(bytes.TrimLeft(data, " \t\r\n,")))
+	li.lines += bytes.Count(li.slice(li.pos, target), []byte{'\n'})
+	li.pos = target
+	li.discard(target)
+	return li.lines + 1, target
+}
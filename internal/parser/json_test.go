@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Reads a JSON array and reports invalid elements with their line
+func TestReadJSON(t *testing.T) {
+	jsonContent := `[
+  {"date": "2023/10/01", "amount": 100, "content": "Groceries"},
+  {"date": "2023/10/02", "amount": -50.0, "content": "Coffee", "category": "Food"},
+  {
+    "date": "2023-10-03",
+    "amount": 1,
+    "content": "Bad date"
+  },
+  {"date": "2023/10/04", "amount": true, "content": "Bad amount"}
+]`
+
+	transactions, rowErrors, err := ReadJSON(strings.NewReader(jsonContent), Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/10/01", Amount: 100, Content: "Groceries"},
+		{Date: "2023/10/02", Amount: -50, Content: "Coffee", Category: "Food"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+
+	if len(rowErrors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", rowErrors)
+	}
+	if rowErrors[0].Line != 4 || rowErrors[0].Column != "date" {
+		t.Errorf("expected a date error at line 4, got %+v", rowErrors[0])
+	}
+	if rowErrors[1].Line != 9 || rowErrors[1].Column != "amount" {
+		t.Errorf("expected an amount error at line 9, got %+v", rowErrors[1])
+	}
+}
+
+// Stores metadata under normalized keys and rejects keys differing by case
+func TestReadJSONMetadataKeys(t *testing.T) {
+	ndjsonContent := `{"date": "2023/10/01", "amount": -100, "content": "Groceries", "metadata": {"Channel": "ATM", "Card  Type": "Debit"}}
+{"date": "2023/10/02", "amount": -200, "content": "Rent", "metadata": {"Channel": "ATM", "CHANNEL": "POS"}}
+`
+
+	transactions, rowErrors, err := ReadNDJSON(strings.NewReader(ndjsonContent), Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/10/01", Amount: -100, Content: "Groceries", Metadata: map[string]string{"channel": "ATM", "card type": "Debit"}},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 2 || rowErrors[0].Column != "metadata" {
+		t.Errorf("expected a metadata error at line 2, got %v", rowErrors)
+	}
+}
+
+// Reads newline-delimited JSON, skipping blank lines
+func TestReadNDJSON(t *testing.T) {
+	ndjsonContent := `{"date": "2023/10/01", "amount": 100, "content": "Groceries"}
+
+{"date": "2023/10/02", "amount": -200, "content": "Rent"}
+{"date": "2023/10/03", "amount": -200
+`
+
+	transactions, rowErrors, err := ReadNDJSON(strings.NewReader(ndjsonContent), Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/10/01", Amount: 100, Content: "Groceries"},
+		{Date: "2023/10/02", Amount: -200, Content: "Rent"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 4 {
+		t.Errorf("expected an error at line 4, got %v", rowErrors)
+	}
+}
+
+// Records the line and byte offset of each array element with provenance
+func TestReadJSONProvenance(t *testing.T) {
+	jsonContent := "[\n  {\"date\": \"2023/10/01\", \"amount\": 100, \"content\": \"Groceries\"},\n  {\"date\": \"2023/10/02\", \"amount\": -200, \"content\": \"Rent\"}\n]\n"
+
+	transactions, _, err := ReadJSON(strings.NewReader(jsonContent), Options{Provenance: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []*transaction.Source{{Line: 2, Offset: offset(4)}, {Line: 3, Offset: offset(69)}}
+	for i, tx := range transactions {
+		if !reflect.DeepEqual(tx.Source, expected[i]) {
+			t.Errorf("expected %v, got %v", expected[i], tx.Source)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// mt940StatementLine matches the packed start of a :61: field: value date
+// YYMMDD, optional entry date MMDD, debit/credit mark (with R for
+// reversals), optional funds code and the amount with a decimal comma.
+var mt940StatementLine = regexp.MustCompile(`^(\d{6})(\d{4})?(RC|RD|C|D)([A-Z])?(\d+,\d*)`)
+
+// mt940Field is a tag and its value, with continuation lines joined.
+type mt940Field struct {
+	tag    string
+	value  string
+	line   int
+	offset int64
+}
+
+// ReadMT940 reads the :61: statement lines of a SWIFT MT940 file, using the
+// :86: information field that follows each of them as its content.
+func ReadMT940(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	reader := bufio.NewReader(file)
+
+	var transactions []transaction.Transaction
+	var rowErrors []RowError
+
+	var statement *mt940Field // :61: waiting for its :86:
+	var field *mt940Field     // field being read
+	line := opts.LineOffset
+	var offset int64
+
+	// flushStatement converts the pending :61: into a transaction.
+	flushStatement := func(info string) error {
+		if statement == nil {
+			return nil
+		}
+		pending := *statement
+		statement = nil
+		tx, problems := mt940ToTransaction(pending, info)
+		if len(problems) == 0 {
+			tx.Source = opts.source(pending.line, pending.offset)
+			transactions = append(transactions, tx)
+			return nil
+		}
+		return collect(&rowErrors, problems, opts)
+	}
+
+	// endField handles a field once all of its continuation lines are read.
+	endField := func() error {
+		if field == nil {
+			return nil
+		}
+		f := field
+		field = nil
+		switch {
+		case f.tag == "61":
+			if err := flushStatement(""); err != nil {
+				return err
+			}
+			statement = f
+		case f.tag == "86":
+			return flushStatement(f.value)
+		default:
+			return flushStatement("")
+		}
+		return nil
+	}
+
+	for {
+		text, err := reader.ReadString('\n')
+		if text == "" && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading MT940 file: %v", err)
+		}
+		line++
+		start := offset
+		offset += int64(len(text))
+		text = strings.TrimRight(text, "\r\n")
+
+		tag, value, isField := parseMT940Tag(text)
+		switch {
+		case isField:
+			if err := endField(); err != nil {
+				return []transaction.Transaction{}, rowErrors, err
+			}
+			field = &mt940Field{tag: tag, value: value, line: line, offset: start}
+		case strings.HasPrefix(text, "-") || strings.HasPrefix(text, "{"):
+			// End of a message or a SWIFT block header.
+			if err := endField(); err != nil {
+				return []transaction.Transaction{}, rowErrors, err
+			}
+		case field != nil && field.tag == "86":
+			field.value += " " + strings.TrimSpace(text)
+		case field != nil:
+			field.value += strings.TrimSpace(text)
+		}
+	}
+	if err := endField(); err != nil {
+		return []transaction.Transaction{}, rowErrors, err
+	}
+	if err := flushStatement(""); err != nil {
+		return []transaction.Transaction{}, rowErrors, err
+	}
+
+	return transactions, rowErrors, nil
+}
+
+// parseMT940Tag splits a line such as ":61:2301150115D150,00NTRF" into its
+// tag and value.
+func parseMT940Tag(text string) (string, string, bool) {
+	if !strings.HasPrefix(text, ":") {
+		return "", "", false
+	}
+	tag, value, ok := strings.Cut(text[1:], ":")
+	if !ok || tag == "" || len(tag) > 3 {
+		return "", "", false
+	}
+	return tag, value, true
+}
+
+// mt940ToTransaction converts a :61: statement line and its :86:
+// information into a Transaction. The value date is used as the date.
+func mt940ToTransaction(statement mt940Field, info string) (transaction.Transaction, []RowError) {
+	m := mt940StatementLine.FindStringSubmatch(statement.value)
+	if m == nil {
+		return transaction.Transaction{}, []RowError{{Line: statement.line, Column: ":61:", Value: statement.value, Reason: "invalid statement line"}}
+	}
+
+	var problems []RowError
+	date, err := time.Parse("060102", m[1])
+	if err != nil {
+		problems = append(problems, RowError{Line: statement.line, Column: ":61:", Value: m[1], Reason: fmt.Sprintf("invalid date format: %v", err)})
+	}
+
+	amount, err := parseDecimalAmount(strings.Replace(m[5], ",", ".", 1))
+	if err != nil {
+		problems = append(problems, RowError{Line: statement.line, Column: ":61:", Value: m[5], Reason: fmt.Sprintf("invalid amount: %v", err)})
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	// Debits and reversals of credits take money out of the account.
+	if m[3] == "D" || m[3] == "RC" {
+		amount = -amount
+	}
+
+	return transaction.Transaction{
+		Date:    date.Format("2006/01/02"),
+		Amount:  amount,
+		Content: strings.TrimSpace(info),
+	}, nil
+}
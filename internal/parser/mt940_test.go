@@ -0,0 +1,54 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Reads :61: statement lines with their multi-line :86: information
+func TestReadMT940(t *testing.T) {
+	mt940Content := `{1:F01BANKBEBBAXXX0000000000}{2:I940BANKBEBBXXXXN}{4:
+:20:STATEMENT1
+:25:123456789
+:28C:00001/001
+:60F:C230101VND1000000,00
+:61:2301150115D150000,00NTRFNONREF//B123
+:86:Electricity bill
+January 2023
+:61:230120CR2000000,NTRFSALARY
+:86:Salary
+:61:230125RC500,00NCHGREF
+:62F:C230131VND2849500,00
+-}
+`
+
+	transactions, _, err := ReadMT940(strings.NewReader(mt940Content), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/01/15", Amount: -150000, Content: "Electricity bill January 2023"},
+		{Date: "2023/01/20", Amount: 2000000, Content: "Salary"},
+		{Date: "2023/01/25", Amount: -500, Content: ""},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Reports malformed statement lines with their line number
+func TestReadMT940InvalidStatementLine(t *testing.T) {
+	mt940Content := ":20:REF\n:61:23011X\n:86:Broken\n"
+
+	_, rowErrors, err := ReadMT940(strings.NewReader(mt940Content), Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 2 {
+		t.Errorf("expected an error at line 2, got %v", rowErrors)
+	}
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// ReadOFX reads the STMTTRN entries of an OFX/QFX statement. Both the SGML
+// flavour of OFX 1.x, where leaf elements are not closed, and the XML flavour
+// of OFX 2.x are supported. DTPOSTED, TRNAMT and NAME/MEMO are mapped to the
+// date, amount and content of a Transaction.
+func ReadOFX(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	reader := bufio.NewReader(file)
+
+	var transactions []transaction.Transaction
+	var rowErrors []RowError
+
+	line := opts.LineOffset + 1
+	trnLine := 0
+	var offset, trnOffset int64  // bytes consumed, start of the STMTTRN tag
+	var fields map[string]string // fields of the STMTTRN being read, nil outside one
+	element := ""                // leaf element whose value comes next
+
+	for {
+		// Text up to the next tag is the value of the previous element.
+		text, err := reader.ReadString('<')
+		line += strings.Count(text, "\n")
+		offset += int64(len(text))
+		if fields != nil && element != "" {
+			if value := strings.TrimSpace(strings.TrimSuffix(text, "<")); value != "" {
+				fields[element] = html.UnescapeString(value)
+			}
+		}
+		element = ""
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading OFX file: %v", err)
+		}
+
+		tagOffset := offset - 1
+		tag, err := reader.ReadString('>')
+		line += strings.Count(tag, "\n")
+		offset += int64(len(tag))
+		if err != nil {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("unterminated OFX tag at line %d", line)
+		}
+		tag = strings.TrimSuffix(tag, ">")
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") || strings.HasSuffix(tag, "/") {
+			continue
+		}
+		name := strings.ToUpper(strings.Fields(tag + " ")[0])
+
+		switch name {
+		case "STMTTRN":
+			fields = map[string]string{}
+			trnLine = line
+			trnOffset = tagOffset
+		case "/STMTTRN":
+			if fields == nil {
+				continue
+			}
+			tx, problems := ofxToTransaction(fields, trnLine)
+			fields = nil
+			if len(problems) == 0 {
+				tx.Source = opts.source(trnLine, trnOffset)
+				transactions = append(transactions, tx)
+				continue
+			}
+			if err := collect(&rowErrors, problems, opts); err != nil {
+				return []transaction.Transaction{}, rowErrors, err
+			}
+		default:
+			if !strings.HasPrefix(name, "/") {
+				element = name
+			}
+		}
+	}
+
+	if fields != nil {
+		return []transaction.Transaction{}, rowErrors, fmt.Errorf("unterminated STMTTRN starting at line %d", trnLine)
+	}
+
+	return transactions, rowErrors, nil
+}
+
+// ofxToTransaction validates the fields of a STMTTRN and converts them into
+// a Transaction.
+func ofxToTransaction(fields map[string]string, line int) (transaction.Transaction, []RowError) {
+	var problems []RowError
+
+	// DTPOSTED is YYYYMMDD, optionally followed by a time and a timezone.
+	posted := fields["DTPOSTED"]
+	var date time.Time
+	var err error
+	if len(posted) < 8 {
+		err = fmt.Errorf("expected YYYYMMDD")
+	} else {
+		date, err = time.Parse("20060102", posted[:8])
+	}
+	if err != nil {
+		problems = append(problems, RowError{Line: line, Column: "DTPOSTED", Value: posted, Reason: fmt.Sprintf("invalid date format: %v", err)})
+	}
+
+	amount, err := parseDecimalAmount(fields["TRNAMT"])
+	if err != nil {
+		problems = append(problems, RowError{Line: line, Column: "TRNAMT", Value: fields["TRNAMT"], Reason: fmt.Sprintf("invalid amount: %v", err)})
+	}
+
+	content := fields["NAME"]
+	if memo := fields["MEMO"]; memo != "" && memo != content {
+		if content == "" {
+			content = memo
+		} else {
+			content = content + " - " + memo
+		}
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	return transaction.Transaction{
+		Date:    date.Format("2006/01/02"),
+		Amount:  amount,
+		Content: content,
+	}, nil
+}
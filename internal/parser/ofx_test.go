@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Reads STMTTRN entries of an OFX 1.x SGML statement with unclosed leaf elements
+func TestReadOFXSGML(t *testing.T) {
+	ofxContent := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20230115120000.000[-5:EST]
+<TRNAMT>-50000.00
+<NAME>Grab &amp; Go
+<MEMO>Lunch
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20230120
+<TRNAMT>1500000
+<MEMO>Salary
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+	transactions, _, err := ReadOFX(strings.NewReader(ofxContent), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/01/15", Amount: -50000, Content: "Grab & Go - Lunch"},
+		{Date: "2023/01/20", Amount: 1500000, Content: "Salary"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Reads STMTTRN entries of an OFX 2.x XML statement
+func TestReadOFXXML(t *testing.T) {
+	ofxContent := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20230301</DTPOSTED><TRNAMT>-12.00</TRNAMT><NAME>Coffee</NAME></STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	transactions, _, err := ReadOFX(strings.NewReader(ofxContent), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/03/01", Amount: -12, Content: "Coffee"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Reports invalid entries with their line in lenient mode
+func TestReadOFXLenient(t *testing.T) {
+	ofxContent := "<OFX>\n<STMTTRN>\n<DTPOSTED>2023\n<TRNAMT>-1\n</STMTTRN>\n" +
+		"<STMTTRN>\n<DTPOSTED>20230102\n<TRNAMT>-2\n<NAME>Ok\n</STMTTRN>\n</OFX>\n"
+
+	transactions, rowErrors, err := ReadOFX(strings.NewReader(ofxContent), Options{Lenient: true})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(transactions) != 1 {
+		t.Errorf("expected 1 transaction, got %v", transactions)
+	}
+	if len(rowErrors) != 1 || rowErrors[0].Line != 2 || rowErrors[0].Column != "DTPOSTED" {
+		t.Errorf("expected a DTPOSTED error at line 2, got %v", rowErrors)
+	}
+}
@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// qifListTypes are QIF sections holding lists rather than transactions.
+var qifListTypes = map[string]bool{
+	"!account":        true,
+	"!type:cat":       true,
+	"!type:class":     true,
+	"!type:memorized": true,
+	"!type:prices":    true,
+	"!type:security":  true,
+}
+
+// ReadQIF reads the transactions of a QIF file. The D (date), T or U
+// (amount), P (payee), M (memo) and L (category) fields are used, other
+// fields such as splits are ignored.
+func ReadQIF(file io.Reader, opts Options) ([]transaction.Transaction, []RowError, error) {
+	reader := bufio.NewReader(file)
+
+	var transactions []transaction.Transaction
+	var rowErrors []RowError
+
+	line := opts.LineOffset
+	recordLine := 0
+	var offset, recordOffset int64
+	skip := false
+	fields := map[byte]string{}
+
+	for {
+		text, err := reader.ReadString('\n')
+		if text == "" && err == io.EOF {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return []transaction.Transaction{}, rowErrors, fmt.Errorf("error reading QIF file: %v", err)
+		}
+		line++
+		start := offset
+		offset += int64(len(text))
+
+		text = strings.TrimRight(text, "\r\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		// A header selects the type of the records that follow.
+		if text[0] == '!' {
+			header := strings.ToLower(strings.TrimSpace(text))
+			skip = qifListTypes[header] || strings.HasPrefix(header, "!option") || strings.HasPrefix(header, "!clear")
+			fields = map[byte]string{}
+			continue
+		}
+
+		if text[0] != '^' {
+			if len(fields) == 0 {
+				recordLine = line
+				recordOffset = start
+			}
+			// Keep the first occurrence, later ones belong to splits.
+			if _, ok := fields[text[0]]; !ok {
+				fields[text[0]] = strings.TrimSpace(text[1:])
+			}
+			continue
+		}
+
+		record := fields
+		fields = map[byte]string{}
+		if skip || len(record) == 0 {
+			continue
+		}
+
+		tx, problems := qifToTransaction(record, recordLine)
+		if len(problems) == 0 {
+			tx.Source = opts.source(recordLine, recordOffset)
+			transactions = append(transactions, tx)
+			continue
+		}
+		if err := collect(&rowErrors, problems, opts); err != nil {
+			return []transaction.Transaction{}, rowErrors, err
+		}
+	}
+
+	return transactions, rowErrors, nil
+}
+
+// qifToTransaction validates the fields of a QIF record and converts them
+// into a Transaction.
+func qifToTransaction(fields map[byte]string, line int) (transaction.Transaction, []RowError) {
+	var problems []RowError
+
+	date, err := parseQIFDate(fields['D'])
+	if err != nil {
+		problems = append(problems, RowError{Line: line, Column: "D", Value: fields['D'], Reason: fmt.Sprintf("invalid date format: %v", err)})
+	}
+
+	amountField, amountStr := byte('T'), fields['T']
+	if amountStr == "" {
+		amountField, amountStr = 'U', fields['U']
+	}
+	amount, err := parseDecimalAmount(strings.ReplaceAll(amountStr, ",", ""))
+	if err != nil {
+		problems = append(problems, RowError{Line: line, Column: string(amountField), Value: amountStr, Reason: fmt.Sprintf("invalid amount: %v", err)})
+	}
+
+	if len(problems) > 0 {
+		return transaction.Transaction{}, problems
+	}
+
+	content := fields['P']
+	if memo := fields['M']; memo != "" && memo != content {
+		if content == "" {
+			content = memo
+		} else {
+			content = content + " - " + memo
+		}
+	}
+
+	return transaction.Transaction{
+		Date:     date.Format("2006/01/02"),
+		Amount:   amount,
+		Content:  content,
+		Category: fields['L'],
+	}, nil
+}
+
+// parseQIFDate parses the date variants found in QIF files: month first
+// with '/', '-' or '.' separators, two or four digit years, the "'" year
+// notation for years from 2000 (1/15'23) and ISO dates.
+func parseQIFDate(s string) (time.Time, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), " ", "")
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	apostrophe := strings.Contains(s, "'")
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\''
+	})
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+	}
+
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+		}
+		nums[i] = n
+	}
+	month, day, year := nums[0], nums[1], nums[2]
+
+	if len(parts[2]) <= 2 {
+		switch {
+		case apostrophe:
+			year += 2000
+		case year < 70:
+			year += 2000
+		default:
+			year += 1900
+		}
+	}
+
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if month < 1 || month > 12 || t.Day() != day {
+		return time.Time{}, fmt.Errorf("date out of range %q", s)
+	}
+	return t, nil
+}
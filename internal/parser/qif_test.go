@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Reads QIF bank records and skips list sections
+func TestReadQIF(t *testing.T) {
+	qifContent := `!Type:Cat
+NFood
+D Food expenses
+^
+!Type:Bank
+D1/15'23
+T-1,250.00
+PSupermarket
+MWeekly groceries
+LFood:Groceries
+^
+D02/01/2023
+U3,000,000.00
+PEmployer
+LSalary
+^
+`
+
+	transactions, _, err := ReadQIF(strings.NewReader(qifContent), Options{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := []transaction.Transaction{
+		{Date: "2023/01/15", Amount: -1250, Content: "Supermarket - Weekly groceries", Category: "Food:Groceries"},
+		{Date: "2023/02/01", Amount: 3000000, Content: "Employer", Category: "Salary"},
+	}
+	if !reflect.DeepEqual(transactions, expected) {
+		t.Errorf("expected %v, got %v", expected, transactions)
+	}
+}
+
+// Parses the common QIF date variants
+func TestParseQIFDate(t *testing.T) {
+	cases := map[string]string{
+		"1/15'23":    "2023/01/15",
+		" 1/ 5'02":   "2002/01/05",
+		"01/15/2023": "2023/01/15",
+		"12/31/99":   "1999/12/31",
+		"3-7-2024":   "2024/03/07",
+		"2023-04-30": "2023/04/30",
+	}
+	for input, expected := range cases {
+		date, err := parseQIFDate(input)
+		if err != nil {
+			t.Errorf("%q: expected no error, got %v", input, err)
+			continue
+		}
+		if got := date.Format("2006/01/02"); got != expected {
+			t.Errorf("%q: expected %s, got %s", input, expected, got)
+		}
+	}
+
+	for _, input := range []string{"", "13/01/2023", "2/30/2023", "today"} {
+		if _, err := parseQIFDate(input); err == nil {
+			t.Errorf("%q: expected an error, got nil", input)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"sync"
+)
+
+// RejectWriter writes rejected records to a quarantine CSV. Each record is
+// copied byte-for-byte with an extra column holding the rejection reason.
+// It is safe for concurrent use: every record is written in a single call
+// so lines from different workers never interleave.
+type RejectWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewRejectWriter returns a RejectWriter writing to w.
+func NewRejectWriter(w io.Writer) *RejectWriter {
+	return &RejectWriter{w: w}
+}
+
+// Write appends the reason column to the raw record and writes it out.
+func (rw *RejectWriter) Write(raw []byte, reason string) error {
+	// Split off the line terminator so the reason lands on the same line.
+	body := bytes.TrimRight(raw, "\r\n")
+	terminator := raw[len(body):]
+	if len(terminator) == 0 {
+		terminator = []byte{'\n'}
+	}
+
+	var line bytes.Buffer
+	line.Write(body)
+	line.WriteByte(',')
+	w := csv.NewWriter(&line)
+	if err := w.Write([]string{reason}); err != nil {
+		return err
+	}
+	w.Flush()
+	line.Truncate(line.Len() - 1) // drop the csv writer's own newline
+	line.Write(terminator)
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	_, err := rw.w.Write(line.Bytes())
+	return err
+}
+
+// recorder keeps the bytes read through it so that the raw text of a
+// record can be recovered from its input offsets.
+type recorder struct {
+	r    io.Reader
+	buf  []byte
+	base int64
+}
+
+func (rc *recorder) Read(p []byte) (int, error) {
+	n, err := rc.r.Read(p)
+	rc.buf = append(rc.buf, p[:n]...)
+	return n, err
+}
+
+// slice returns the input between the offsets start and end.
+func (rc *recorder) slice(start, end int64) []byte {
+	return rc.buf[start-rc.base : end-rc.base]
+}
+
+// discard drops the input before offset. The buffer is only compacted once
+// most of it is stale, to avoid copying on every record.
+func (rc *recorder) discard(offset int64) {
+	consumed := int(offset - rc.base)
+	if consumed < len(rc.buf)/2 {
+		return
+	}
+	rc.buf = append(rc.buf[:0], rc.buf[consumed:]...)
+	rc.base = offset
+}
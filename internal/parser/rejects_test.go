@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Copies rejected records byte-for-byte and appends the rejection reason
+func TestReadTransactionsWritesRejects(t *testing.T) {
+	csvContent := "2023/10/01,100,Groceries\r\n" +
+		"2023/10/02,  12x,\"Rent, October\"\r\n" +
+		"2023/10/03,300,Salary\r\n"
+
+	var out bytes.Buffer
+	opts := Options{Lenient: true, LineOffset: 1, Rejects: NewRejectWriter(&out)}
+	transactions, _, err := ReadTransactions(strings.NewReader(csvContent), []string{"date", "amount", "content"}, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Errorf("expected 2 transactions, got %d", len(transactions))
+	}
+
+	expected := "2023/10/02,  12x,\"Rent, October\",\"amount: invalid amount: strconv.Atoi: parsing \"\"12x\"\": invalid syntax\"\r\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+// Copies rejected NDJSON lines and appends the rejection reason
+func TestReadNDJSONWritesRejects(t *testing.T) {
+	ndjsonContent := `{"date": "2023/10/01", "amount": 100, "content": "Groceries"}
+{"date": "2023/10/02", "amount": -200
+`
+
+	var out bytes.Buffer
+	opts := Options{Lenient: true, Rejects: NewRejectWriter(&out)}
+	transactions, _, err := ReadNDJSON(strings.NewReader(ndjsonContent), opts)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(transactions) != 1 {
+		t.Errorf("expected 1 transaction, got %d", len(transactions))
+	}
+
+	expected := `{"date": "2023/10/02", "amount": -200,invalid JSON object: unexpected end of JSON input` + "\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+// Writes whole lines when used from several goroutines
+func TestRejectWriterConcurrentWrites(t *testing.T) {
+	var out bytes.Buffer
+	rw := NewRejectWriter(&out)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := rw.Write([]byte("2023/10/02,abc,Rent\n"), "bad amount"); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		if line != "2023/10/02,abc,Rent,bad amount" {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string   `xml:"t"`
+		Runs []string `xml:"r>t"`
+	} `xml:"si"`
+}
+
+type xlsxStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxRow struct {
+	Index int        `xml:"r,attr"`
+	Cells []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref        string   `xml:"r,attr"`
+	Type       string   `xml:"t,attr"`
+	Style      int      `xml:"s,attr"`
+	Value      string   `xml:"v"`
+	Inline     string   `xml:"is>t"`
+	InlineRuns []string `xml:"is>r>t"`
+}
+
+// xlsxBook holds the workbook parts needed to read cell values.
+type xlsxBook struct {
+	date1904   bool
+	strings    []string
+	dateStyles map[int]bool
+	sheetPath  string
+	sheetName  string
+}
+
+// XLSXToCSV renders a worksheet of an Excel workbook as CSV text, using
+// only the standard library, so that its rows are read like the records of
+// a CSV file. Options.Sheet selects the worksheet by name or 1-based
+// position, the first one is used by default.
+//
+// Leading rows with fewer cells than ExpectedHeaders, such as titles, are
+// dropped and counted in skipped, as are the empty columns before the first
+// cell of the first row kept. Later rows keep their row numbers as line
+// numbers: blank and missing rows become empty lines, which CSV readers
+// skip. Dates are written in the CSV date layout, including serial numbers
+// without a date style in a column holding dates in other rows.
+func XLSXToCSV(file io.Reader, opts Options) (text []byte, skipped int, err error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error reading XLSX file: %v", err)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, 0, fmt.Errorf("error opening XLSX file: %v", err)
+	}
+
+	book, err := openXLSXBook(archive, opts.Sheet)
+	if err != nil {
+		return nil, 0, err
+	}
+	rows, err := book.readRows(archive)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	first := slices.IndexFunc(rows, func(row xlsxValues) bool {
+		return row.cells() >= len(ExpectedHeaders)
+	})
+	if first < 0 {
+		return nil, 0, fmt.Errorf("no row with %d or more cells found in sheet '%s'", len(ExpectedHeaders), book.sheetName)
+	}
+	rows = rows[first:]
+	start := slices.IndexFunc(rows[0].values, func(v xlsxValue) bool { return strings.TrimSpace(v.text) != "" })
+	end := len(rows[0].values)
+	for end > start && strings.TrimSpace(rows[0].values[end-1].text) == "" {
+		end--
+	}
+
+	dateColumns := map[int]bool{}
+	for _, row := range rows {
+		for column, value := range row.values {
+			dateColumns[column] = dateColumns[column] || value.date
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	line := rows[0].index
+	for _, row := range rows {
+		for ; line < row.index; line++ {
+			buf.WriteByte('\n')
+		}
+		line++
+		if row.cells() == 0 {
+			buf.WriteByte('\n')
+			continue
+		}
+
+		record := make([]string, end-start)
+		for i := range record {
+			column := start + i
+			if column >= len(row.values) {
+				continue
+			}
+			value := row.values[column]
+			record[i] = value.text
+			if value.numeric && !value.date && dateColumns[column] {
+				record[i] = book.serialDate(value.number)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return nil, 0, err
+		}
+		w.Flush()
+	}
+
+	return buf.Bytes(), rows[0].index - 1, nil
+}
+
+// xlsxValues holds the values of a row by column position.
+type xlsxValues struct {
+	index  int
+	values []xlsxValue
+}
+
+// cells returns the number of cells holding a value.
+func (r xlsxValues) cells() int {
+	n := 0
+	for _, value := range r.values {
+		if strings.TrimSpace(value.text) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// readRows returns the rows of the sheet with their 1-based row numbers.
+func (b *xlsxBook) readRows(archive *zip.Reader) ([]xlsxValues, error) {
+	sheet, err := archive.Open(b.sheetPath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sheet '%s': %v", b.sheetName, err)
+	}
+	defer sheet.Close()
+
+	var rows []xlsxValues
+	decoder := xml.NewDecoder(sheet)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading sheet '%s': %v", b.sheetName, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := decoder.DecodeElement(&row, &start); err != nil {
+			return nil, fmt.Errorf("error reading sheet '%s': %v", b.sheetName, err)
+		}
+		// Rows without a number follow the previous one.
+		index := row.Index
+		if len(rows) > 0 && index <= rows[len(rows)-1].index {
+			index = rows[len(rows)-1].index + 1
+		}
+		rows = append(rows, xlsxValues{index: max(index, 1), values: b.rowValues(row)})
+	}
+}
+
+// openXLSXBook reads the workbook parts and resolves the selected sheet.
+func openXLSXBook(archive *zip.Reader, sheet string) (*xlsxBook, error) {
+	var workbook xlsxWorkbook
+	if err := readXLSXPart(archive, "xl/workbook.xml", &workbook, true); err != nil {
+		return nil, err
+	}
+	var rels xlsxRelationships
+	if err := readXLSXPart(archive, "xl/_rels/workbook.xml.rels", &rels, true); err != nil {
+		return nil, err
+	}
+	var sst xlsxSharedStrings
+	if err := readXLSXPart(archive, "xl/sharedStrings.xml", &sst, false); err != nil {
+		return nil, err
+	}
+	var styles xlsxStyles
+	if err := readXLSXPart(archive, "xl/styles.xml", &styles, false); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, fmt.Errorf("XLSX file has no sheets")
+	}
+
+	book := &xlsxBook{
+		date1904:   workbook.Properties.Date1904,
+		dateStyles: map[int]bool{},
+	}
+
+	// Pick the sheet by name, then by position.
+	selected := -1
+	if sheet == "" {
+		selected = 0
+	}
+	for i, s := range workbook.Sheets {
+		if selected < 0 && strings.EqualFold(s.Name, sheet) {
+			selected = i
+		}
+	}
+	if n, err := strconv.Atoi(sheet); selected < 0 && err == nil && n >= 1 && n <= len(workbook.Sheets) {
+		selected = n - 1
+	}
+	if selected < 0 {
+		return nil, fmt.Errorf("sheet '%s' not found in XLSX file", sheet)
+	}
+	book.sheetName = workbook.Sheets[selected].Name
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[selected].RID {
+			if strings.HasPrefix(rel.Target, "/") {
+				book.sheetPath = strings.TrimPrefix(rel.Target, "/")
+			} else {
+				book.sheetPath = path.Join("xl", rel.Target)
+			}
+		}
+	}
+	if book.sheetPath == "" {
+		return nil, fmt.Errorf("sheet '%s' has no worksheet part", book.sheetName)
+	}
+
+	for _, item := range sst.Items {
+		book.strings = append(book.strings, item.Text+strings.Join(item.Runs, ""))
+	}
+
+	customFormats := map[int]string{}
+	for _, f := range styles.NumFmts {
+		customFormats[f.ID] = f.Code
+	}
+	for i, xf := range styles.CellXfs {
+		book.dateStyles[i] = isXLSXDateFormat(xf.NumFmtID, customFormats[xf.NumFmtID])
+	}
+
+	return book, nil
+}
+
+// readXLSXPart decodes an XML part of the archive. Optional parts may be
+// missing.
+func readXLSXPart(archive *zip.Reader, name string, v any, required bool) error {
+	f, err := archive.Open(name)
+	if err != nil {
+		if required {
+			return fmt.Errorf("invalid XLSX file: missing %s", name)
+		}
+		return nil
+	}
+	defer f.Close()
+	if err := xml.NewDecoder(f).Decode(v); err != nil {
+		return fmt.Errorf("error reading %s: %v", name, err)
+	}
+	return nil
+}
+
+// isXLSXDateFormat reports whether a number format displays a date. The
+// built-in formats 14 to 22 and 45 to 47 are dates and times, custom
+// formats are dates when they use day or year placeholders.
+func isXLSXDateFormat(id int, code string) bool {
+	if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) {
+		return true
+	}
+	if code == "" {
+		return false
+	}
+	// Ignore quoted literals and bracketed sections such as colors.
+	var plain strings.Builder
+	quoted, bracketed := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case !quoted && r == '[':
+			bracketed = true
+		case !quoted && r == ']':
+			bracketed = false
+		case !quoted && !bracketed:
+			plain.WriteRune(r)
+		}
+	}
+	return strings.ContainsAny(plain.String(), "dy")
+}
+
+// xlsxValue is a cell value, as text and, for numeric cells, as number.
+type xlsxValue struct {
+	text    string
+	number  float64
+	numeric bool
+	date    bool
+}
+
+// rowValues returns the values of a row by column position.
+func (b *xlsxBook) rowValues(row xlsxRow) []xlsxValue {
+	var values []xlsxValue
+	for i, cell := range row.Cells {
+		column := i
+		if cell.Ref != "" {
+			column = xlsxColumnIndex(cell.Ref)
+		}
+		for len(values) <= column {
+			values = append(values, xlsxValue{})
+		}
+		values[column] = b.cellValue(cell)
+	}
+	return values
+}
+
+// cellValue converts a cell into text: shared and inline strings are looked
+// up, dates are formatted as YYYY/MM/DD and whole numbers lose their
+// decimal point.
+func (b *xlsxBook) cellValue(cell xlsxCell) xlsxValue {
+	switch cell.Type {
+	case "s":
+		i, err := strconv.Atoi(cell.Value)
+		if err != nil || i < 0 || i >= len(b.strings) {
+			return xlsxValue{text: cell.Value}
+		}
+		return xlsxValue{text: b.strings[i]}
+	case "inlineStr":
+		return xlsxValue{text: cell.Inline + strings.Join(cell.InlineRuns, "")}
+	case "d":
+		// ISO 8601 date cell.
+		if t, err := time.Parse("2006-01-02", cell.Value[:min(len(cell.Value), 10)]); err == nil {
+			return xlsxValue{text: t.Format("2006/01/02"), date: true}
+		}
+		return xlsxValue{text: cell.Value}
+	case "str", "b", "e":
+		return xlsxValue{text: cell.Value}
+	}
+
+	number, err := strconv.ParseFloat(cell.Value, 64)
+	if err != nil {
+		return xlsxValue{text: cell.Value}
+	}
+	if b.dateStyles[cell.Style] {
+		return xlsxValue{text: b.serialDate(number), number: number, numeric: true, date: true}
+	}
+	text := strconv.FormatFloat(number, 'f', -1, 64)
+	if number == math.Trunc(number) && math.Abs(number) < math.MaxInt64 {
+		text = strconv.FormatInt(int64(number), 10)
+	}
+	return xlsxValue{text: text, number: number, numeric: true}
+}
+
+// serialDate converts an Excel serial date into the YYYY/MM/DD layout.
+func (b *xlsxBook) serialDate(serial float64) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if b.date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return epoch.AddDate(0, 0, int(math.Floor(serial))).Format("2006/01/02")
+}
+
+// xlsxColumnIndex returns the 0-based column of a cell reference like "AB12".
+func xlsxColumnIndex(ref string) int {
+	column := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		column = column*26 + int(r-'A'+1)
+	}
+	return column - 1
+}
@@ -0,0 +1,79 @@
+package parser
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// buildXLSX returns a minimal workbook with the given sheet XML as "Export".
+func buildXLSX(t *testing.T, sheetXML string) *bytes.Reader {
+	t.Helper()
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Summary" sheetId="1" r:id="rId1"/><sheet name="Export" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     `<sst><si><t>Date</t></si><si><t>Amount</t></si><si><t>Content</t></si><si><r><t>Gro</t></r><r><t>ceries</t></r></si></sst>`,
+		"xl/styles.xml":            `<styleSheet><numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy"/></numFmts><cellXfs><xf numFmtId="0"/><xf numFmtId="164"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData/></worksheet>`,
+		"xl/worksheets/sheet2.xml": sheetXML,
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// Renders the selected sheet from its first full row as CSV, keeping row
+// numbers and converting serial dates and numbers
+func TestXLSXToCSV(t *testing.T) {
+	sheetXML := `<worksheet><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>Account statement</t></is></c></row>
+<row r="3"><c r="B3" t="s"><v>0</v></c><c r="C3" t="s"><v>2</v></c><c r="D3" t="s"><v>1</v></c></row>
+<row r="4"><c r="B4" s="1"><v>44941</v></c><c r="C4" t="s"><v>3</v></c><c r="D4"><v>-150000</v></c></row>
+<row r="5"><c r="B5" t="str"><v>2023/01/20</v></c><c r="C5" t="inlineStr"><is><t>Salary, January</t></is></c><c r="D5"><v>2000000</v></c></row>
+<row r="7"><c r="B7"><v>44950</v></c><c r="C7" t="inlineStr"><is><t>Refund</t></is></c><c r="D7"><v>12.5</v></c></row>
+<row r="8"/>
+</sheetData></worksheet>`
+
+	text, skipped, err := XLSXToCSV(buildXLSX(t, sheetXML), Options{Sheet: "export"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	expected := "Date,Content,Amount\n2023/01/15,Groceries,-150000\n2023/01/20,\"Salary, January\",2000000\n\n2023/01/24,Refund,12.5\n\n"
+	if string(text) != expected || skipped != 2 {
+		t.Errorf("expected %q after 2 rows, got %q after %d", expected, text, skipped)
+	}
+
+	header, records, _ := strings.Cut(string(text), "\n")
+	opts := Options{Lenient: true, LineOffset: skipped + 1, Columns: []string{"date", "content", "amount"}}
+	transactions, rowErrors, err := ReadTransactions(strings.NewReader(records), []string{"date", "amount", "content"}, opts)
+	if err != nil || header != "Date,Content,Amount" {
+		t.Fatalf("expected the header and no error, got %q and %v", header, err)
+	}
+	if len(transactions) != 2 || len(rowErrors) != 1 || rowErrors[0].Line != 7 || rowErrors[0].Column != "amount" {
+		t.Errorf("expected 2 transactions and an amount error at row 7, got %v and %v", transactions, rowErrors)
+	}
+}
+
+// Fails when no row has a cell for each expected header
+func TestXLSXToCSVMissingHeader(t *testing.T) {
+	sheetXML := `<worksheet><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c></row></sheetData></worksheet>`
+
+	if _, _, err := XLSXToCSV(buildXLSX(t, sheetXML), Options{Sheet: "2"}); err == nil {
+		t.Error("expected an error, got nil")
+	}
+}
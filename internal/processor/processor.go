@@ -3,14 +3,18 @@ package processor
 import (
 	"bufio"
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"reflect"
+	"slices"
 	"strings"
 
+	"github.com/tonghia/transaction-history/internal/config"
 	"github.com/tonghia/transaction-history/internal/parser"
+	"github.com/tonghia/transaction-history/internal/query"
 	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
@@ -18,58 +22,219 @@ type part struct {
 	offset, size int64
 }
 
-var expectedHeaders = []string{"date", "amount", "content"}
+// partResult is the outcome of processing one part of a split file.
+type partResult struct {
+	index  int
+	lines  int
+	result Result
+	err    error
+}
+
+// Options holds the optional settings of Process.
+type Options struct {
+	Parser parser.Options
+	// NoHeader reads CSV files without a header row, using Parser.Columns
+	// for the layout of the records.
+	NoHeader bool
+	// Encoding of text input, transcoded to UTF-8 before parsing. Empty or
+	// parser.EncodingAuto detects it.
+	Encoding string
+	// Metadata keeps only the transactions whose fields have these values.
+	Metadata map[string]string
+	// Include keeps only the transactions whose content matches one of
+	// these patterns, and Exclude drops those matching one of them.
+	Include []transaction.Pattern
+	Exclude []transaction.Pattern
+	// Amount keeps only the transactions of its direction and size.
+	Amount transaction.AmountFilter
+	// Sort orders the transactions, by date descending when empty.
+	Sort []transaction.SortKey
+	// Page selects the transactions to output, per month when the summary
+	// is split by month. Totals cover all the transactions.
+	Page transaction.Page
+	// Where keeps only the transactions satisfying the query, if any.
+	Where *query.Query
+	// GroupBy totals the transactions by the value of this field, or splits
+	// the summary by month when it is GroupByMonth.
+	GroupBy string
+	// Profile configures the format and the header names of CSV input.
+	Profile *config.Profile
+	// Profiles are detected from the header of CSV input that neither has
+	// the expected header nor comes with a Profile.
+	Profiles []config.Profile
+	// Format names the registered input format, detected from the file
+	// when empty.
+	Format string
+	// Calendar sets where the months of summaries per month begin.
+	Calendar transaction.Calendar
+
+	// bom is the size of the byte order mark skipped at the start of the file.
+	bom int64
+	// skipped is the number of rows dropped from the start of converted
+	// input.
+	skipped int
+}
+
+// Result represents the JSON output: the summary and, in lenient mode, the
+// problems found in the skipped records.
+type Result struct {
+	transaction.Summary
+	Errors []parser.RowError `json:"errors,omitempty"`
+}
 
-func Process(filePath string, yearMonth string, workerNum int) (json.RawMessage, error) {
+// MonthlyResult represents the JSON output split by month: the grand
+// totals of the period and a summary per month.
+type MonthlyResult struct {
+	Period           string                `json:"period"`
+	TotalIncome      int                   `json:"total_income"`
+	TotalExpenditure int                   `json:"total_expenditure"`
+	Months           []transaction.Summary `json:"months"`
+	GroupBy          string                `json:"group_by,omitempty"`
+	Groups           []transaction.Group   `json:"groups,omitempty"`
+	Filters          *transaction.Filters  `json:"filters,omitempty"`
+	Errors           []parser.RowError     `json:"errors,omitempty"`
+}
+
+// GroupByMonth splits the summary into one per month. It is implied by the
+// period parser.PeriodAll.
+const GroupByMonth = "month"
+
+var expectedHeaders = parser.ExpectedHeaders
+
+func Process(filePath string, period transaction.Period, workerNum int, opts Options) (json.RawMessage, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("error opening CSV file: %v", err)
+		return nil, fmt.Errorf("error opening input file: %v", err)
 	}
 	defer file.Close()
 
-	reader := bufio.NewReader(file)
-	header, err := reader.ReadString('\n')
-	if err != nil {
-		return nil, fmt.Errorf("error reading header: %v", err)
+	head := make([]byte, 64*1024)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("error reading input file: %v", err)
 	}
-	for i, header := range strings.Split(header, ",") {
-		if strings.TrimSpace(strings.ToLower(header)) != expectedHeaders[i] {
-			return nil, fmt.Errorf("unexpected header: expected '%s', got '%s'", expectedHeaders[i], header)
+	head = head[:n]
+
+	format := parser.DetectFormat(filePath, head)
+	if opts.Format != "" {
+		format, err = parser.LookupFormat(opts.Format)
+		if err != nil {
+			return nil, err
 		}
 	}
+	if opts.Parser.Rejects != nil && !format.Rejects {
+		return nil, fmt.Errorf("rejected records cannot be written for %s input", format.Name)
+	}
 
-	var summary transaction.Summary
-	if workerNum <= 1 {
-		rs, err := ProcessData(reader, yearMonth)
+	// Converted input is CSV text in UTF-8, read as a whole. Its offsets do
+	// not locate records in the file.
+	var input io.Reader = file
+	converted := format.Convert != nil
+	if converted {
+		text, skipped, err := format.Convert(file, opts.Parser)
 		if err != nil {
-			return nil, fmt.Errorf("error processing CSV file: %v", err)
+			return nil, fmt.Errorf("error converting %s file: %v", format.Name, err)
 		}
-		summary = rs
-	} else {
-		// Determine non-overlapping parts for file split (each part has offset and size).
-		parts, err := splitFile(filePath, workerNum, len(header))
+		input = bytes.NewReader(text)
+		head = text[:min(len(text), len(head))]
+		workerNum = 1
+		opts.skipped = skipped
+		opts.Encoding = parser.EncodingUTF8
+		opts.Parser.Transcoded = true
+		opts.Profile, opts.Profiles = commaProfiles(opts.Profile, opts.Profiles)
+	}
+
+	if opts.Profile != nil {
+		opts = withProfile(opts, *opts.Profile)
+	} else if format.Layout == parser.LayoutCSV && (!format.Binary || converted) && !opts.NoHeader && opts.Parser.Columns == nil {
+		// Detect the profile before choosing the decoder, which depends
+		// on the encoding of the profile.
+		profile, ok, err := detectProfile(head, opts)
 		if err != nil {
-			return nil, fmt.Errorf("error spliting file")
+			return nil, err
+		}
+		if ok {
+			opts = withProfile(opts, profile)
+		}
+	}
+
+	monthly := opts.GroupBy == GroupByMonth || period.Label == parser.PeriodAll
+	if opts.GroupBy == GroupByMonth {
+		opts.GroupBy = ""
+	}
+
+	// Transcode text input to UTF-8.
+	if !format.Binary {
+		opts.Encoding, err = resolveEncoding(head, opts.Encoding)
+		if err != nil {
+			return nil, err
+		}
+		input = parser.DecodeReader(file, opts.Encoding)
+		opts.Parser.Transcoded = opts.Encoding != parser.EncodingUTF8
+		if opts.Parser.Provenance && !opts.Parser.Transcoded {
+			opts.bom, err = bomSize(file, opts.Encoding)
+			if err != nil {
+				return nil, err
+			}
 		}
-		// Start a goroutine to process each part, returning results on a channel.
-		resultsCh := make(chan transaction.Summary)
-		for _, part := range parts {
-			go processPart(filePath, part.offset, part.size, yearMonth, resultsCh)
+	}
+	opts.Parser.ByteOffset = opts.bom
+
+	var result Result
+	switch format.Layout {
+	case parser.LayoutCSV:
+		result, err = processCSV(input, filePath, period, workerNum, opts, format.Importer)
+		if err != nil {
+			return nil, err
+		}
+	case parser.LayoutLines:
+		result, err = processLines(input, filePath, 0, 0, period, workerNum, opts, format.Importer)
+		if err != nil {
+			return nil, err
 		}
+	default:
+		// Documents are read as a whole, -workernum only applies to line formats.
+		result, err = processTransactions(input, period, opts, format.Importer)
+		if err != nil {
+			return nil, fmt.Errorf("error processing %s file: %v", format.Name, err)
+		}
+	}
 
-		for i := 0; i < len(parts); i++ {
-			result := <-resultsCh
-			summary.TotalIncome = summary.TotalIncome + result.TotalIncome
-			summary.TotalExpenditure = summary.TotalExpenditure + result.TotalExpenditure
-			summary.Transactions = append(summary.Transactions, result.Transactions...)
+	for _, tx := range result.Transactions {
+		if tx.Source != nil {
+			tx.Source.File = filePath
 		}
+	}
+
+	result.Filters = activeFilters(opts)
 
-		summary.Period = yearMonth
-		transaction.SortTransactions(summary.Transactions)
+	var output any
+	if monthly {
+		months := transaction.SummarizeMonths(result.Transactions, opts.GroupBy, opts.Calendar)
+		if opts.Page != (transaction.Page{}) {
+			for i := range months {
+				months[i].Transactions, months[i].Paging = opts.Page.Apply(months[i].Transactions)
+			}
+		}
+		output = MonthlyResult{
+			Period:           result.Period,
+			TotalIncome:      result.TotalIncome,
+			TotalExpenditure: result.TotalExpenditure,
+			Months:           months,
+			GroupBy:          result.GroupBy,
+			Groups:           result.Groups,
+			Filters:          result.Filters,
+			Errors:           result.Errors,
+		}
+	} else {
+		if opts.Page != (transaction.Page{}) {
+			result.Transactions, result.Paging = opts.Page.Apply(result.Transactions)
+		}
+		output = result
 	}
 
 	// Generate JSON output.
-	jsonData, err := json.MarshalIndent(summary, "", "  ")
+	jsonData, err := json.MarshalIndent(output, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling JSON: %v", err)
 	}
@@ -77,45 +242,358 @@ func Process(filePath string, yearMonth string, workerNum int) (json.RawMessage,
 	return jsonData, nil
 }
 
-func ProcessData(file io.Reader, yearMonth string) (transaction.Summary, error) {
-	// Parse the test period
-	year, month, err := parser.ParseYearMonth(yearMonth)
+// activeFilters records the filters of the options, or returns nil when
+// there are none.
+func activeFilters(opts Options) *transaction.Filters {
+	filters := transaction.Filters{
+		Metadata:  opts.Metadata,
+		MinAmount: opts.Amount.Min,
+		MaxAmount: opts.Amount.Max,
+		Direction: opts.Amount.Direction,
+	}
+	if opts.Where != nil {
+		filters.Where = opts.Where.String()
+	}
+	for _, p := range opts.Include {
+		filters.Include = append(filters.Include, p.String())
+	}
+	for _, p := range opts.Exclude {
+		filters.Exclude = append(filters.Exclude, p.String())
+	}
+	if reflect.DeepEqual(filters, transaction.Filters{}) {
+		return nil
+	}
+	return &filters
+}
+
+// processCSV checks the header of a CSV file and processes its records,
+// splitting the file into parts when more than one worker is requested.
+func processCSV(file io.Reader, filePath string, period transaction.Period, workerNum int, opts Options, importer parser.Importer) (Result, error) {
+	if opts.NoHeader {
+		// Records start at byte 0, also for the parts of a split file.
+		return processLines(file, filePath, 0, opts.skipped, period, workerNum, opts, importer)
+	}
+
+	reader := bufio.NewReader(file)
+	header, err := reader.ReadString('\n')
 	if err != nil {
-		return transaction.Summary{}, nil
+		return Result{}, fmt.Errorf("error reading header: %v", err)
 	}
+	columns, err := checkHeader(header, opts.Parser.Columns, opts.Profile)
+	if err != nil {
+		if opts.Parser.Rejects != nil {
+			if err := opts.Parser.Rejects.Write([]byte(header), err.Error()); err != nil {
+				return Result{}, fmt.Errorf("error writing rejected header: %v", err)
+			}
+		}
+		return Result{}, err
+	}
+	opts.Parser.Columns = columns
 
-	// Read and parse the CSV file.
-	transactions, err := parser.CSVtoTransactions(file, expectedHeaders)
+	// The parts of a split file are located in the raw bytes, which differ
+	// from the decoded header for other encodings than UTF-8.
+	headerSize := len(header)
+	if workerNum > 1 {
+		headerSize, err = firstLineSize(filePath, parser.Newline(opts.Encoding))
+		if err != nil {
+			return Result{}, fmt.Errorf("error reading header: %v", err)
+		}
+	}
+
+	return processLines(reader, filePath, headerSize, opts.skipped+1, period, workerNum, opts, importer)
+}
+
+// detectProfile returns the profile of a known bank whose header starts the
+// file, unless the header holds the expected columns. The header is decoded
+// with the encoding of each profile, unless one is requested.
+func detectProfile(head []byte, opts Options) (config.Profile, bool, error) {
+	encoding, err := resolveEncoding(head, opts.Encoding)
 	if err != nil {
-		return transaction.Summary{}, err
+		return config.Profile{}, false, err
+	}
+	firstLine := func(encoding string) string {
+		line, _ := bufio.NewReader(parser.DecodeReader(bytes.NewReader(head), encoding)).ReadString('\n')
+		return line
+	}
+	if _, err := checkHeader(firstLine(encoding), nil, nil); err == nil {
+		return config.Profile{}, false, nil
+	}
+
+	auto := opts.Encoding == "" || opts.Encoding == parser.EncodingAuto
+	for _, profile := range opts.Profiles {
+		profileEncoding := encoding
+		if auto && profile.Encoding != "" {
+			profileEncoding = profile.Encoding
+		}
+		if _, ok := config.DetectProfile([]config.Profile{profile}, firstLine(profileEncoding)); ok {
+			return profile, true, nil
+		}
+	}
+	return config.Profile{}, false, nil
+}
+
+// commaProfiles returns copies of the profiles using the comma of converted
+// CSV text as delimiter.
+func commaProfiles(profile *config.Profile, profiles []config.Profile) (*config.Profile, []config.Profile) {
+	if profile != nil {
+		p := *profile
+		p.Delimiter = ""
+		profile = &p
+	}
+	profiles = slices.Clone(profiles)
+	for i := range profiles {
+		profiles[i].Delimiter = ""
 	}
+	return profile, profiles
+}
 
-	// Filter transactions based on the specified year and month.
-	filteredTransactions := transaction.FilterTransactions(transactions, year, month)
+// withProfile applies the settings of an import profile to opts. Its
+// encoding is used unless one is requested.
+func withProfile(opts Options, profile config.Profile) Options {
+	opts.Profile = &profile
+	opts.Parser = profile.ParserOptions(opts.Parser)
+	if profile.Encoding != "" && (opts.Encoding == "" || opts.Encoding == parser.EncodingAuto) {
+		opts.Encoding = profile.Encoding
+	}
+	return opts
+}
+
+// resolveEncoding validates the requested encoding, or detects it from the
+// start of the file when it is empty or parser.EncodingAuto.
+func resolveEncoding(head []byte, requested string) (string, error) {
+	encoding, err := parser.ParseEncoding(requested)
+	if err != nil {
+		return "", err
+	}
+	if encoding != parser.EncodingAuto {
+		return encoding, nil
+	}
+	return parser.DetectEncoding(head), nil
+}
+
+// bomSize returns the size of the byte order mark at the start of the file,
+// which DecodeReader skips.
+func bomSize(file *os.File, encoding string) (int64, error) {
+	bom := parser.BOM(encoding)
+	head := make([]byte, len(bom))
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("error reading input file: %v", err)
+	}
+	if len(bom) == 0 || !bytes.Equal(head[:n], bom) {
+		return 0, nil
+	}
+	return int64(len(bom)), nil
+}
+
+// firstLineSize returns the size in bytes of the first line of a file,
+// including its line feed encoded as newline.
+func firstLineSize(filePath string, newline []byte) (int, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var line []byte
+	for {
+		b, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		line = append(line, b)
+		if end := len(line) - len(newline); end >= 0 && end%len(newline) == 0 && bytes.Equal(line[end:], newline) {
+			return len(line), nil
+		}
+	}
+}
+
+// processLines processes the records of a line-oriented file. The reader is
+// positioned after the header, which is headerSize bytes and headerLines
+// lines long. With more than one worker the rest of the file is split into
+// parts processed concurrently.
+func processLines(reader io.Reader, filePath string, headerSize int, headerLines int, period transaction.Period, workerNum int, opts Options, importer parser.Importer) (Result, error) {
+	if workerNum <= 1 {
+		opts.Parser.LineOffset = headerLines
+		opts.Parser.ByteOffset = opts.bom + int64(headerSize)
+		result, err := processTransactions(reader, period, opts, importer)
+		if err != nil {
+			return Result{}, fmt.Errorf("error processing input file: %v", err)
+		}
+		return result, nil
+	}
+
+	// Determine non-overlapping parts for file split (each part has offset and size).
+	parts, err := splitFile(filePath, workerNum, headerSize, parser.Newline(opts.Encoding))
+	if err != nil {
+		return Result{}, fmt.Errorf("error spliting file")
+	}
+	// Start a goroutine to process each part, returning results on a channel.
+	resultsCh := make(chan partResult)
+	for i, part := range parts {
+		go processPart(filePath, i, part.offset, part.size, period, opts, importer, resultsCh)
+	}
+
+	partResults := make([]partResult, len(parts))
+	for i := 0; i < len(parts); i++ {
+		pr := <-resultsCh
+		partResults[pr.index] = pr
+	}
+	for _, pr := range partResults {
+		if pr.err != nil {
+			return Result{}, pr.err
+		}
+	}
+
+	// Merge in file order so that line numbers can be made absolute.
+	var result Result
+	summary := &result.Summary
+	lineOffset := headerLines
+	for _, pr := range partResults {
+		summary.TotalIncome = summary.TotalIncome + pr.result.TotalIncome
+		summary.TotalExpenditure = summary.TotalExpenditure + pr.result.TotalExpenditure
+		for _, tx := range pr.result.Transactions {
+			if tx.Source != nil {
+				tx.Source.Line += lineOffset
+			}
+		}
+		summary.Transactions = transaction.MergeSorted(summary.Transactions, pr.result.Transactions, opts.Sort)
+		summary.Groups = transaction.MergeGroups(summary.Groups, pr.result.Groups)
+		for _, rowErr := range pr.result.Errors {
+			rowErr.Line += lineOffset
+			result.Errors = append(result.Errors, rowErr)
+		}
+		lineOffset += pr.lines
+	}
+	if opts.Parser.MaxErrors > 0 && len(result.Errors) > opts.Parser.MaxErrors {
+		return Result{}, fmt.Errorf("error processing input file: %w: more than %d problems found", parser.ErrTooManyErrors, opts.Parser.MaxErrors)
+	}
+
+	summary.Period = period.Label
+	summary.GroupBy = opts.GroupBy
+
+	return result, nil
+}
+
+// checkHeader verifies that the header line names the expected columns and
+// returns the column names, which it takes from the header unless they are
+// given. Given columns must match the header, except skipped ones. Other
+// columns than the expected ones hold metadata. A profile maps the header
+// names of a bank to the expected ones.
+func checkHeader(header string, columns []string, profile *config.Profile) ([]string, error) {
+	names, err := headerNames(header, profile)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected header: %v", err)
+	}
+
+	if columns != nil {
+		if len(names) != len(columns) {
+			return nil, fmt.Errorf("unexpected header: expected %d columns, got %d", len(columns), len(names))
+		}
+		for i, name := range names {
+			if columns[i] != parser.SkipColumn && name != columns[i] {
+				return nil, fmt.Errorf("unexpected header: expected '%s', got '%s'", columns[i], name)
+			}
+		}
+		return columns, nil
+	}
+
+	for i, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("unexpected header: empty name for column %d", i+1)
+		}
+		if name != parser.SkipColumn && slices.Index(names[:i], name) >= 0 {
+			return nil, fmt.Errorf("unexpected header: duplicate column '%s'", name)
+		}
+	}
+	if missing := parser.MissingColumn(names, expectedHeaders); missing != "" {
+		return nil, fmt.Errorf("unexpected header: missing column '%s'", missing)
+	}
+	return names, nil
+}
+
+// headerNames splits a header line into lowercase column names, mapped to
+// fields by the profile if there is one.
+func headerNames(header string, profile *config.Profile) ([]string, error) {
+	if profile != nil {
+		names, err := profile.HeaderNames(header)
+		if err != nil {
+			return nil, err
+		}
+		for i, name := range names {
+			names[i] = profile.Field(name)
+		}
+		return names, nil
+	}
+
+	reader := csv.NewReader(strings.NewReader(header))
+	reader.TrimLeadingSpace = true
+	names, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = strings.TrimSpace(strings.ToLower(name))
+	}
+	return names, nil
+}
+
+func ProcessData(file io.Reader, period transaction.Period, opts parser.Options) (Result, error) {
+	return processTransactions(file, period, Options{Parser: opts}, parser.CSVFormat.Importer)
+}
+
+// processTransactions reads the transactions with the importer, then filters, totals
+// and sorts them for the given period.
+func processTransactions(file io.Reader, period transaction.Period, opts Options, importer parser.Importer) (Result, error) {
+	// Read and parse the input.
+	transactions, rowErrors, err := importer.Read(file, opts.Parser)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Filter transactions based on the specified period.
+	filteredTransactions := transaction.FilterTransactions(transactions, period)
+	filteredTransactions = transaction.FilterMetadata(filteredTransactions, opts.Metadata)
+	filteredTransactions = transaction.FilterContent(filteredTransactions, opts.Include, opts.Exclude)
+	filteredTransactions = transaction.FilterAmount(filteredTransactions, opts.Amount)
+	if opts.Where != nil {
+		filteredTransactions = opts.Where.Filter(filteredTransactions)
+	}
 
 	// Calculate total income and expenditure.
 	totalIncome, totalExpenditure := transaction.CalculateTotals(filteredTransactions)
 
-	// Sort transactions in descending order by date.
-	transaction.SortTransactions(filteredTransactions)
+	// Sort transactions, by default in descending order by date.
+	transaction.SortTransactions(filteredTransactions, opts.Sort)
 
-	return transaction.Summary{
-		Period:           fmt.Sprintf("%04d/%02d", year, month),
+	summary := transaction.Summary{
+		Period:           period.Label,
 		TotalIncome:      totalIncome,
 		TotalExpenditure: totalExpenditure,
 		Transactions:     filteredTransactions,
+	}
+	if opts.GroupBy != "" {
+		summary.GroupBy = opts.GroupBy
+		summary.Groups = transaction.GroupTransactions(filteredTransactions, opts.GroupBy)
+	}
+
+	return Result{
+		Summary: summary,
+		Errors:  rowErrors,
 	}, nil
 }
 
 // splitFile splits a file into multiple parts based on the specified number of parts
 // refer to https://github.com/benhoyt/go-1brc/blob/fafba3256ea28631f6b3739f6d3b711a91199861/r8.go#L124
-func splitFile(inputPath string, numParts int, initOffset int) ([]part, error) {
+func splitFile(inputPath string, numParts int, initOffset int, newline []byte) ([]part, error) {
 	const maxLineLength = 100 // Assumption
 
 	f, err := os.Open(inputPath)
 	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
 	st, err := f.Stat()
 	if err != nil {
 		return nil, err
@@ -129,7 +607,9 @@ func splitFile(inputPath string, numParts int, initOffset int) ([]part, error) {
 	offset := int64(initOffset)
 	for offset < size {
 		seekOffset := max(offset+splitSize-maxLineLength, offset)
-		if seekOffset > size {
+		if seekOffset+maxLineLength >= size {
+			// The rest of the file, including a last line without `\n`, goes into the last part
+			parts = append(parts, part{offset, size - offset})
 			break
 		}
 		_, err := f.Seek(seekOffset, io.SeekStart)
@@ -138,13 +618,13 @@ func splitFile(inputPath string, numParts int, initOffset int) ([]part, error) {
 		}
 		n, _ := io.ReadFull(f, buf)
 		chunk := buf[:n]
-		newline := bytes.LastIndexByte(chunk, '\n')
-		if newline < 0 {
-			// Case 1: there is no `\n` at the end of the file, we stop
-			// Case 2: maxLineLength is too small for the line, we accept there will be a huge chunk and improve it later
+		end := lastLineEnd(chunk, seekOffset, newline)
+		if end < 0 {
+			// maxLineLength is too small for the line, we accept there will be a huge chunk and improve it later
+			parts = append(parts, part{offset, size - offset})
 			break
 		}
-		remaining := len(chunk) - newline - 1
+		remaining := len(chunk) - end
 		nextOffset := seekOffset + int64(len(chunk)) - int64(remaining)
 		parts = append(parts, part{offset, nextOffset - offset})
 		offset = nextOffset
@@ -153,22 +633,57 @@ func splitFile(inputPath string, numParts int, initOffset int) ([]part, error) {
 	return parts, nil
 }
 
-func processPart(inputPath string, fileOffset int64, fileSize int64, yearMonth string, resultsCh chan<- transaction.Summary) {
+// lastLineEnd returns the position in chunk right after its last newline, or
+// -1 if there is none. Newlines of multi-byte encodings must start at a code
+// unit boundary, counted from the start of the file at chunkOffset.
+func lastLineEnd(chunk []byte, chunkOffset int64, newline []byte) int {
+	for i := bytes.LastIndex(chunk, newline); i >= 0; i = bytes.LastIndex(chunk[:i+len(newline)-1], newline) {
+		if (chunkOffset+int64(i))%int64(len(newline)) == 0 {
+			return i + len(newline)
+		}
+	}
+	return -1
+}
+
+// processPart processes one part of a split file and sends the outcome,
+// or the error it failed with, on resultsCh.
+func processPart(inputPath string, index int, fileOffset int64, fileSize int64, period transaction.Period, opts Options, importer parser.Importer, resultsCh chan<- partResult) {
 	file, err := os.Open(inputPath)
 	if err != nil {
-		log.Fatalf("Error opening CSV file: %v", err)
+		resultsCh <- partResult{index: index, err: fmt.Errorf("error opening input file: %v", err)}
+		return
 	}
 	defer file.Close()
 	if _, err := file.Seek(fileOffset, io.SeekStart); err != nil {
-		log.Fatalf("Error seeking to offset: %v", err)
+		resultsCh <- partResult{index: index, err: fmt.Errorf("error seeking to offset: %v", err)}
+		return
 	}
 
-	f := io.LimitReader(file, fileSize)
+	// Line numbers are relative to the part; Process makes them absolute.
+	f := &lineCounter{r: parser.DecodeReader(io.LimitReader(file, fileSize), opts.Encoding)}
+	opts.Parser.LineOffset = 0
+	opts.Parser.ByteOffset = fileOffset
+	if fileOffset == 0 {
+		opts.Parser.ByteOffset += opts.bom
+	}
 
-	summary, err := ProcessData(f, yearMonth)
+	result, err := processTransactions(f, period, opts, importer)
 	if err != nil {
-		log.Fatalf("Error processing CSV file: %v", err)
+		resultsCh <- partResult{index: index, err: fmt.Errorf("error processing input file: %v", err)}
+		return
 	}
 
-	resultsCh <- summary
+	resultsCh <- partResult{index: index, lines: f.lines, result: result}
+}
+
+// lineCounter counts the newlines read through it.
+type lineCounter struct {
+	r     io.Reader
+	lines int
+}
+
+func (c *lineCounter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.lines += bytes.Count(p[:n], []byte{'\n'})
+	return n, err
 }
@@ -0,0 +1,159 @@
+package query
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// typeOperators are the comparison operators defined on each type.
+var typeOperators = map[Type][]string{
+	TypeNumber:  {"=", "==", "!=", "<", "<=", ">", ">=", "in", "not in", "exists"},
+	TypeString:  {"=", "==", "!=", "~", "!~", "in", "not in", "exists"},
+	TypeDate:    {"=", "==", "!=", "<", "<=", ">", ">=", "in", "not in", "exists"},
+	TypeWeekday: {"=", "==", "!=", "in", "not in", "exists"},
+}
+
+// check type-checks the comparisons of a syntax tree and prepares their
+// evaluation.
+func check(source string, node Node) error {
+	switch n := node.(type) {
+	case *Logical:
+		if err := check(source, n.Left); err != nil {
+			return err
+		}
+		return check(source, n.Right)
+	case *Not:
+		return check(source, n.X)
+	case *Comparison:
+		return compile(source, n)
+	}
+	return nil
+}
+
+func compile(source string, c *Comparison) error {
+	typ, ok := FieldType(c.Field)
+	if !ok {
+		return errorAt(source, c.FieldPos, "unknown field '%s', expected date, amount, content, category, weekday or meta.<name>", c.Field)
+	}
+	if !slices.Contains(typeOperators[typ], c.Op) {
+		return errorAt(source, c.OpPos, "operator '%s' is not defined on %s, which is a %s", c.Op, c.Field, typ)
+	}
+
+	if c.Op == "exists" {
+		c.match = func(tx transaction.Transaction) bool {
+			_, ok := fieldValue(tx, c.Field)
+			return ok
+		}
+		return nil
+	}
+
+	if c.Op == "~" || c.Op == "!~" {
+		lit := c.Values[0]
+		if lit.Kind != TokenString {
+			return errorAt(source, lit.TextPos, "expected a quoted regular expression, got '%s'", lit.Text)
+		}
+		pattern, err := transaction.ParsePattern("re:" + lit.Text)
+		if err != nil {
+			return errorAt(source, lit.TextPos, "%v", err)
+		}
+		c.match = func(tx transaction.Transaction) bool {
+			value, ok := fieldValue(tx, c.Field)
+			return ok && pattern.Match(value.(string)) == (c.Op == "~")
+		}
+		return nil
+	}
+
+	values := make([]any, len(c.Values))
+	for i, lit := range c.Values {
+		value, err := literalValue(source, lit, typ)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+
+	c.match = func(tx transaction.Transaction) bool {
+		value, ok := fieldValue(tx, c.Field)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case "in", "not in":
+			found := slices.ContainsFunc(values, func(v any) bool {
+				return compare(value, v) == 0
+			})
+			return found == (c.Op == "in")
+		}
+		order := compare(value, values[0])
+		switch c.Op {
+		case "!=":
+			return order != 0
+		case "<":
+			return order < 0
+		case "<=":
+			return order <= 0
+		case ">":
+			return order > 0
+		case ">=":
+			return order >= 0
+		}
+		return order == 0
+	}
+	return nil
+}
+
+// literalValue converts a literal to a value of the type, as returned by
+// fieldValue.
+func literalValue(source string, lit Literal, typ Type) (any, error) {
+	switch typ {
+	case TypeNumber:
+		if lit.Kind == TokenNumber {
+			if n, err := strconv.Atoi(strings.ReplaceAll(lit.Text, "_", "")); err == nil {
+				return n, nil
+			}
+		}
+		return nil, errorAt(source, lit.TextPos, "expected a number, got '%s'", lit.Text)
+	case TypeString:
+		if lit.Kind == TokenNumber {
+			return nil, errorAt(source, lit.TextPos, "expected a string, got '%s'", lit.Text)
+		}
+		return transaction.Fold(lit.Text), nil
+	case TypeDate:
+		if lit.Kind != TokenIdent {
+			date, err := time.Parse("2006-01-02", strings.ReplaceAll(lit.Text, "/", "-"))
+			if err == nil {
+				return date, nil
+			}
+		}
+		return nil, errorAt(source, lit.TextPos, "expected a date as YYYY-MM-DD, got '%s'", lit.Text)
+	case TypeWeekday:
+		name := strings.ToLower(lit.Text)
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			if len(name) >= 3 && strings.HasPrefix(strings.ToLower(day.String()), name) {
+				return day, nil
+			}
+		}
+		return nil, errorAt(source, lit.TextPos, "expected a weekday such as mon or monday, got '%s'", lit.Text)
+	}
+	return nil, errorAt(source, lit.TextPos, "unexpected value '%s'", lit.Text)
+}
+
+// compare orders two values of the same type.
+func compare(a, b any) int {
+	switch a := a.(type) {
+	case int:
+		return cmp.Compare(a, b.(int))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case time.Weekday:
+		return cmp.Compare(a, b.(time.Weekday))
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TokenKind is the kind of a token of an expression.
+type TokenKind int
+
+const (
+	TokenEOF TokenKind = iota
+	TokenIdent
+	TokenNumber
+	TokenString
+	TokenOp
+	TokenLParen
+	TokenRParen
+	TokenComma
+)
+
+// Token is a lexical token of an expression.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  int
+}
+
+func (t Token) String() string {
+	switch t.Kind {
+	case TokenEOF:
+		return "end of expression"
+	case TokenString:
+		return fmt.Sprintf("string %q", t.Text)
+	}
+	return fmt.Sprintf("'%s'", t.Text)
+}
+
+// operators are the comparison operators, longest first.
+var operators = []string{"==", "!=", "<=", ">=", "!~", "=", "<", ">", "~"}
+
+func lex(source string) ([]Token, error) {
+	var tokens []Token
+	for pos := 0; pos < len(source); {
+		r, size := utf8.DecodeRuneInString(source[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+		case r == '(':
+			tokens = append(tokens, Token{TokenLParen, "(", pos})
+			pos++
+		case r == ')':
+			tokens = append(tokens, Token{TokenRParen, ")", pos})
+			pos++
+		case r == ',':
+			tokens = append(tokens, Token{TokenComma, ",", pos})
+			pos++
+		case r == '"' || r == '\'':
+			text, end, err := lexString(source, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{TokenString, text, pos})
+			pos = end
+		case isDigit(r) || (r == '-' && pos+1 < len(source) && isDigit(rune(source[pos+1]))):
+			end := pos + 1
+			for end < len(source) && (isDigit(rune(source[end])) || strings.ContainsRune("_-/", rune(source[end]))) {
+				end++
+			}
+			tokens = append(tokens, Token{TokenNumber, source[pos:end], pos})
+			pos = end
+		case unicode.IsLetter(r) || r == '_':
+			end := pos
+			for end < len(source) {
+				r, size := utf8.DecodeRuneInString(source[end:])
+				if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '.' {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, Token{TokenIdent, source[pos:end], pos})
+			pos = end
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(source[pos:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorAt(source, pos, "unexpected character %q", r)
+			}
+			tokens = append(tokens, Token{TokenOp, op, pos})
+			pos += len(op)
+		}
+	}
+	return append(tokens, Token{TokenEOF, "", len(source)}), nil
+}
+
+// lexString reads the string literal starting at pos, in which a backslash
+// escapes the quote and itself, and returns its text and end.
+func lexString(source string, pos int) (string, int, error) {
+	quote := source[pos]
+	var b strings.Builder
+	for i := pos + 1; i < len(source); i++ {
+		switch c := source[i]; {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(source) && (source[i+1] == quote || source[i+1] == '\\'):
+			i++
+			b.WriteByte(source[i])
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errorAt(source, pos, "unterminated string")
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
@@ -0,0 +1,181 @@
+package query
+
+import (
+	"strings"
+)
+
+// syntaxParser builds the syntax tree of an expression by recursive
+// descent:
+//
+//	or         = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | "(" or ")" | comparison
+//	comparison = field op literal | field [ "not" ] "in" "(" literal { "," literal } ")"
+type syntaxParser struct {
+	source string
+	tokens []Token
+	pos    int
+}
+
+func (p *syntaxParser) peek() Token {
+	return p.tokens[p.pos]
+}
+
+func (p *syntaxParser) next() Token {
+	tok := p.tokens[p.pos]
+	if tok.Kind != TokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the keyword, and consumes it
+// if so.
+func (p *syntaxParser) keyword(word string) bool {
+	if tok := p.peek(); tok.Kind == TokenIdent && strings.EqualFold(tok.Text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *syntaxParser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		opPos := p.peek().Pos
+		if !p.keyword("or") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "or", Left: left, Right: right, OpPos: opPos}
+	}
+}
+
+func (p *syntaxParser) parseAnd() (Node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		opPos := p.peek().Pos
+		if !p.keyword("and") {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "and", Left: left, Right: right, OpPos: opPos}
+	}
+}
+
+func (p *syntaxParser) parseNot() (Node, error) {
+	notPos := p.peek().Pos
+	if p.keyword("not") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x, NotPos: notPos}, nil
+	}
+
+	if p.peek().Kind == TokenLParen {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.Kind != TokenRParen {
+			return nil, errorAt(p.source, tok.Pos, "expected ')', got %s", tok)
+		}
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *syntaxParser) parseComparison() (Node, error) {
+	field := p.next()
+	if field.Kind != TokenIdent || isKeyword(field.Text) {
+		return nil, errorAt(p.source, field.Pos, "expected a field, got %s", field)
+	}
+	comparison := &Comparison{Field: fieldName(field.Text), FieldPos: field.Pos, OpPos: p.peek().Pos}
+
+	switch {
+	case p.keyword("exists"):
+		comparison.Op = "exists"
+		return comparison, nil
+	case p.peek().Kind == TokenOp:
+		comparison.Op = p.next().Text
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = []Literal{value}
+		return comparison, nil
+	case p.keyword("in"):
+		comparison.Op = "in"
+	case p.keyword("not"):
+		if !p.keyword("in") {
+			tok := p.peek()
+			return nil, errorAt(p.source, tok.Pos, "expected 'in' after 'not', got %s", tok)
+		}
+		comparison.Op = "not in"
+	default:
+		tok := p.peek()
+		return nil, errorAt(p.source, tok.Pos, "expected an operator after '%s', got %s", field.Text, tok)
+	}
+
+	if tok := p.next(); tok.Kind != TokenLParen {
+		return nil, errorAt(p.source, tok.Pos, "expected '(' after '%s', got %s", comparison.Op, tok)
+	}
+	for {
+		value, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		comparison.Values = append(comparison.Values, value)
+		tok := p.next()
+		if tok.Kind == TokenRParen {
+			return comparison, nil
+		}
+		if tok.Kind != TokenComma {
+			return nil, errorAt(p.source, tok.Pos, "expected ',' or ')', got %s", tok)
+		}
+	}
+}
+
+func (p *syntaxParser) parseLiteral() (Literal, error) {
+	tok := p.next()
+	switch {
+	case tok.Kind == TokenNumber, tok.Kind == TokenString:
+	case tok.Kind == TokenIdent && !isKeyword(tok.Text):
+	default:
+		return Literal{}, errorAt(p.source, tok.Pos, "expected a value, got %s", tok)
+	}
+	return Literal{Kind: tok.Kind, Text: tok.Text, TextPos: tok.Pos}, nil
+}
+
+// fieldName lowercases a field name, except the name of a metadata key
+// after "meta.", which keeps its case for messages. Metadata keys are
+// looked up by transaction.MetadataKey.
+func fieldName(text string) string {
+	if prefix, name, ok := strings.Cut(text, "."); ok && strings.EqualFold(prefix, "meta") {
+		return "meta." + name
+	}
+	return strings.ToLower(text)
+}
+
+func isKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", "in", "exists":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,224 @@
+// Package query parses and evaluates -where expressions filtering
+// transactions, such as
+//
+//	amount < -50000 and content ~ "rent|electric" and weekday in (sat, sun)
+//
+// Comparisons take a field on the left and literals on the right, and are
+// combined with and, or, not and parentheses. The fields are date, amount,
+// content, category, weekday and meta.<name> for metadata. String
+// comparisons ignore case and diacritics, and ~ matches a regular
+// expression. Comparisons of a missing field, such as an empty category or
+// an absent metadata key, are false; "meta.<name> exists" checks that it is
+// present.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Query is a checked expression, ready to be evaluated.
+type Query struct {
+	source string
+	root   Node
+}
+
+// Type is the type of a field.
+type Type int
+
+const (
+	TypeNumber Type = iota + 1
+	TypeString
+	TypeDate
+	TypeWeekday
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeNumber:
+		return "number"
+	case TypeString:
+		return "string"
+	case TypeDate:
+		return "date"
+	case TypeWeekday:
+		return "weekday"
+	}
+	return "unknown"
+}
+
+// Node is a node of the syntax tree of an expression.
+type Node interface {
+	// Pos is the byte offset of the node in the expression.
+	Pos() int
+}
+
+// Logical combines two expressions with "and" or "or".
+type Logical struct {
+	Op          string
+	Left, Right Node
+	OpPos       int
+}
+
+// Not negates an expression.
+type Not struct {
+	X      Node
+	NotPos int
+}
+
+// Comparison compares a field with one literal, or with a list of them for
+// the operators "in" and "not in".
+type Comparison struct {
+	Field    string
+	FieldPos int
+	Op       string
+	OpPos    int
+	Values   []Literal
+
+	match func(tx transaction.Transaction) bool
+}
+
+// Literal is a number, string, date or bare word of an expression.
+type Literal struct {
+	Kind    TokenKind
+	Text    string
+	TextPos int
+}
+
+func (n *Logical) Pos() int    { return n.Left.Pos() }
+func (n *Not) Pos() int        { return n.NotPos }
+func (n *Comparison) Pos() int { return n.FieldPos }
+
+// Error is a syntax or type error in an expression.
+type Error struct {
+	// Column is the 1-based position of the error in characters.
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+func errorAt(source string, pos int, format string, a ...any) *Error {
+	return &Error{
+		Column: utf8.RuneCountInString(source[:pos]) + 1,
+		Msg:    fmt.Sprintf(format, a...),
+	}
+}
+
+// Parse parses and type-checks an expression.
+func Parse(source string) (*Query, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &syntaxParser{source: source, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.Kind != TokenEOF {
+		return nil, errorAt(source, tok.Pos, "unexpected %s", tok)
+	}
+
+	if err := check(source, root); err != nil {
+		return nil, err
+	}
+	return &Query{source: source, root: root}, nil
+}
+
+// String returns the expression the query was parsed from.
+func (q *Query) String() string {
+	return q.source
+}
+
+// Root returns the syntax tree of the query.
+func (q *Query) Root() Node {
+	return q.root
+}
+
+// Match reports whether a transaction satisfies the query.
+func (q *Query) Match(tx transaction.Transaction) bool {
+	return eval(q.root, tx)
+}
+
+// Filter keeps the transactions satisfying the query.
+func (q *Query) Filter(transactions []transaction.Transaction) []transaction.Transaction {
+	var filtered []transaction.Transaction
+	for _, tx := range transactions {
+		if q.Match(tx) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
+
+func eval(node Node, tx transaction.Transaction) bool {
+	switch n := node.(type) {
+	case *Logical:
+		if n.Op == "and" {
+			return eval(n.Left, tx) && eval(n.Right, tx)
+		}
+		return eval(n.Left, tx) || eval(n.Right, tx)
+	case *Not:
+		return !eval(n.X, tx)
+	case *Comparison:
+		return n.match(tx)
+	}
+	return false
+}
+
+// FieldType returns the type of a field, or false if there is no such
+// field.
+func FieldType(field string) (Type, bool) {
+	switch field {
+	case "amount":
+		return TypeNumber, true
+	case "content", "category":
+		return TypeString, true
+	case "date":
+		return TypeDate, true
+	case "weekday":
+		return TypeWeekday, true
+	}
+	if name, ok := strings.CutPrefix(field, "meta."); ok && name != "" {
+		return TypeString, true
+	}
+	return 0, false
+}
+
+// fieldValue returns the value of a field of the transaction: an int for
+// numbers, a folded string for strings, a time.Time for dates and a
+// time.Weekday for weekdays. ok is false for dates that do not parse and
+// for missing fields.
+func fieldValue(tx transaction.Transaction, field string) (value any, ok bool) {
+	switch field {
+	case "amount":
+		return tx.Amount, true
+	case "date", "weekday":
+		date, err := time.Parse("2006/01/02", tx.Date)
+		if err != nil {
+			return nil, false
+		}
+		if field == "weekday" {
+			return date.Weekday(), true
+		}
+		return date, true
+	}
+	var s string
+	if name, isMeta := strings.CutPrefix(field, "meta."); isMeta {
+		s, ok = tx.Metadata[transaction.MetadataKey(name)]
+	} else {
+		s, ok = tx.Field(field)
+	}
+	if !ok {
+		return nil, false
+	}
+	return transaction.Fold(s), true
+}
@@ -0,0 +1,104 @@
+package query
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tonghia/transaction-history/internal/transaction"
+)
+
+// Evaluates comparisons combined with and, or, not and parentheses
+func TestMatch(t *testing.T) {
+	rent := transaction.Transaction{Date: "2023/06/03", Amount: -5000000, Content: "Tiền nhà tháng 6", Category: "Rent"}
+	lunch := transaction.Transaction{Date: "2023/06/05", Amount: -45000, Content: "Ăn trưa", Metadata: map[string]string{"account": "Cash"}}
+	salary := transaction.Transaction{Date: "2023/06/25", Amount: 20000000, Content: "Salary"}
+
+	tests := []struct {
+		expr     string
+		expected []bool
+	}{
+		{`amount < -50000 and content ~ "nha|electric" and weekday in (sat, sun)`, []bool{true, false, false}},
+		{`amount >= 0 or meta.account = cash`, []bool{false, true, true}},
+		{`not (category = "rent") and date < 2023-06-25`, []bool{false, true, false}},
+		{`content !~ "^an" and weekday not in (Sunday)`, []bool{true, false, false}},
+		{`date in ("2023/06/05", 2023-06-25)`, []bool{false, true, true}},
+		{`content = "an trua"`, []bool{false, true, false}},
+		{`amount != -45_000`, []bool{true, false, true}},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.expr, err)
+			continue
+		}
+		for i, tx := range []transaction.Transaction{rent, lunch, salary} {
+			if got := q.Match(tx); got != test.expected[i] {
+				t.Errorf("%s on %q: expected %v, got %v", test.expr, tx.Content, test.expected[i], got)
+			}
+		}
+	}
+}
+
+// Looks up metadata keys regardless of case and treats missing fields as
+// false unless checked with exists
+func TestMatchMissingFields(t *testing.T) {
+	tagged := transaction.Transaction{Date: "2023/06/05", Content: "Ăn trưa", Category: "Food", Metadata: map[string]string{"account": "Cash"}}
+	untagged := transaction.Transaction{Date: "2023/06/06", Content: "Salary"}
+
+	tests := []struct {
+		expr     string
+		expected []bool
+	}{
+		{`meta.Account = cash`, []bool{true, false}},
+		{`META.Account != card`, []bool{true, false}},
+		{`meta.ACCOUNT = cash`, []bool{true, false}},
+		{`meta.Account = card`, []bool{false, false}},
+		{`meta.Account !~ "^card" or category not in (rent)`, []bool{true, false}},
+		{`meta.Account exists`, []bool{true, false}},
+		{`not meta.Account exists and not category exists`, []bool{false, true}},
+		{`meta.date exists`, []bool{false, false}},
+	}
+	for _, test := range tests {
+		q, err := Parse(test.expr)
+		if err != nil {
+			t.Errorf("%s: expected no error, got %v", test.expr, err)
+			continue
+		}
+		for i, tx := range []transaction.Transaction{tagged, untagged} {
+			if got := q.Match(tx); got != test.expected[i] {
+				t.Errorf("%s on %q: expected %v, got %v", test.expr, tx.Content, test.expected[i], got)
+			}
+		}
+	}
+}
+
+// Reports syntax and type errors at their column
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr   string
+		column int
+	}{
+		{`amount < `, 10},
+		{`amount < -5 and`, 16},
+		{`amout < -5`, 1},
+		{`content < "a"`, 9},
+		{`amount = "a"`, 10},
+		{`weekday in (sat, fun)`, 18},
+		{`content ~ "(" `, 11},
+		{`(amount < 0`, 12},
+		{`content = "open`, 11},
+		{`ngày = 1`, 1},
+		{`amount < 0 # comment`, 12},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.expr)
+		var queryErr *Error
+		if !errors.As(err, &queryErr) {
+			t.Errorf("%s: expected an error, got %v", test.expr, err)
+			continue
+		}
+		if queryErr.Column != test.column {
+			t.Errorf("%s: expected an error at column %d, got %v", test.expr, test.column, err)
+		}
+	}
+}
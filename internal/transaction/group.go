@@ -0,0 +1,114 @@
+package transaction
+
+import (
+	"math"
+	"sort"
+)
+
+// Group aggregates the transactions sharing the same value of a field.
+// Average and the shares of the total income and expenditure of all groups
+// are derived from the other aggregates, so that groups computed over
+// separate parts can be merged.
+type Group struct {
+	Value            string  `json:"value"`
+	Count            int     `json:"count"`
+	TotalIncome      int     `json:"total_income"`
+	TotalExpenditure int     `json:"total_expenditure"`
+	Sum              int     `json:"sum"`
+	Average          float64 `json:"average"`
+	Min              int     `json:"min"`
+	Max              int     `json:"max"`
+	IncomeShare      float64 `json:"income_share"`
+	ExpenditureShare float64 `json:"expenditure_share"`
+}
+
+// GroupTransactions totals the transactions by the value of a field, sorted
+// by value. Transactions without the field are grouped under "".
+func GroupTransactions(transactions []Transaction, field string) []Group {
+	var groups []Group
+	index := map[string]int{}
+	for _, tx := range transactions {
+		value, _ := tx.Field(field)
+		i, ok := index[value]
+		if !ok {
+			i = len(groups)
+			index[value] = i
+			groups = append(groups, Group{Value: value})
+		}
+		groups[i].add(tx.Amount)
+	}
+
+	finishGroups(groups)
+	return groups
+}
+
+// MergeGroups combines groups computed over separate sets of transactions.
+func MergeGroups(a, b []Group) []Group {
+	merged := append([]Group(nil), a...)
+	index := map[string]int{}
+	for i, g := range merged {
+		index[g.Value] = i
+	}
+	for _, g := range b {
+		i, ok := index[g.Value]
+		if !ok {
+			index[g.Value] = len(merged)
+			merged = append(merged, g)
+			continue
+		}
+		merged[i].Min = min(merged[i].Min, g.Min)
+		merged[i].Max = max(merged[i].Max, g.Max)
+		merged[i].Count += g.Count
+		merged[i].Sum += g.Sum
+		merged[i].TotalIncome += g.TotalIncome
+		merged[i].TotalExpenditure += g.TotalExpenditure
+	}
+
+	finishGroups(merged)
+	return merged
+}
+
+func (g *Group) add(amount int) {
+	if g.Count == 0 || amount < g.Min {
+		g.Min = amount
+	}
+	if g.Count == 0 || amount > g.Max {
+		g.Max = amount
+	}
+	g.Count++
+	g.Sum += amount
+	if amount > 0 {
+		g.TotalIncome += amount
+	} else {
+		g.TotalExpenditure += amount
+	}
+}
+
+// finishGroups sorts groups by value and derives their average and shares.
+func finishGroups(groups []Group) {
+	totalIncome, totalExpenditure := 0, 0
+	for _, g := range groups {
+		totalIncome += g.TotalIncome
+		totalExpenditure += g.TotalExpenditure
+	}
+	for i := range groups {
+		g := &groups[i]
+		g.Average = ratio(g.Sum, g.Count, 2)
+		g.IncomeShare = ratio(g.TotalIncome, totalIncome, 4)
+		g.ExpenditureShare = ratio(g.TotalExpenditure, totalExpenditure, 4)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Value < groups[j].Value
+	})
+}
+
+// ratio divides a by b, rounded to the number of decimals, or returns 0
+// when either is 0.
+func ratio(a, b int, decimals int) float64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	scale := math.Pow10(decimals)
+	return math.Round(float64(a)/float64(b)*scale) / scale
+}
@@ -0,0 +1,39 @@
+package transaction
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Groups transactions by a field and merges groups of separate parts
+func TestGroupTransactionsAndMerge(t *testing.T) {
+	first := GroupTransactions([]Transaction{
+		{Amount: -100, Metadata: map[string]string{"channel": "ATM"}},
+		{Amount: 300, Metadata: map[string]string{"channel": "POS"}},
+		{Amount: -50},
+	}, "channel")
+	second := GroupTransactions([]Transaction{
+		{Amount: -20, Metadata: map[string]string{"channel": "ATM"}},
+	}, "channel")
+
+	merged := MergeGroups(first, second)
+
+	expected := []Group{
+		{Value: "", Count: 1, TotalExpenditure: -50, Sum: -50, Average: -50, Min: -50, Max: -50, ExpenditureShare: 0.2941},
+		{Value: "ATM", Count: 2, TotalExpenditure: -120, Sum: -120, Average: -60, Min: -100, Max: -20, ExpenditureShare: 0.7059},
+		{Value: "POS", Count: 1, TotalIncome: 300, Sum: 300, Average: 300, Min: 300, Max: 300, IncomeShare: 1},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %v, but got %v", expected, merged)
+	}
+
+	all := GroupTransactions([]Transaction{
+		{Amount: -100, Metadata: map[string]string{"channel": "ATM"}},
+		{Amount: 300, Metadata: map[string]string{"channel": "POS"}},
+		{Amount: -50},
+		{Amount: -20, Metadata: map[string]string{"channel": "ATM"}},
+	}, "channel")
+	if !reflect.DeepEqual(all, expected) {
+		t.Errorf("Expected grouping all transactions to give %v, but got %v", expected, all)
+	}
+}
@@ -0,0 +1,156 @@
+package transaction
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Pattern matches the content of transactions regardless of case and
+// diacritics, so that "an trua" matches "Ăn trưa".
+type Pattern struct {
+	text string
+	re   *regexp.Regexp
+}
+
+// ParsePattern parses a pattern: a regular expression when prefixed with
+// "re:", a glob matching the whole content when it holds * or ?, and a
+// substring otherwise.
+func ParsePattern(s string) (Pattern, error) {
+	var expr string
+	switch {
+	case strings.HasPrefix(s, "re:"):
+		expr = FoldDiacritics(strings.TrimPrefix(s, "re:"))
+	case strings.ContainsAny(s, "*?"):
+		var b strings.Builder
+		b.WriteString("^")
+		for _, r := range Fold(s) {
+			switch r {
+			case '*':
+				b.WriteString(".*")
+			case '?':
+				b.WriteString(".")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		b.WriteString("$")
+		expr = b.String()
+	default:
+		expr = regexp.QuoteMeta(Fold(s))
+	}
+
+	re, err := regexp.Compile("(?is)" + expr)
+	if err != nil {
+		return Pattern{}, fmt.Errorf("invalid pattern '%s': %v", s, err)
+	}
+	return Pattern{text: s, re: re}, nil
+}
+
+// String returns the pattern as it was parsed.
+func (p Pattern) String() string {
+	return p.text
+}
+
+// Match reports whether the content matches the pattern.
+func (p Pattern) Match(content string) bool {
+	return p.re.MatchString(Fold(content))
+}
+
+// FilterContent keeps the transactions whose content matches one of the
+// include patterns, if any, and none of the exclude patterns.
+func FilterContent(transactions []Transaction, include []Pattern, exclude []Pattern) []Transaction {
+	if len(include) == 0 && len(exclude) == 0 {
+		return transactions
+	}
+
+	matchesAny := func(patterns []Pattern, content string) bool {
+		for _, p := range patterns {
+			if p.re.MatchString(content) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var filtered []Transaction
+	for _, tx := range transactions {
+		content := Fold(tx.Content)
+		if len(include) > 0 && !matchesAny(include, content) {
+			continue
+		}
+		if matchesAny(exclude, content) {
+			continue
+		}
+		filtered = append(filtered, tx)
+	}
+	return filtered
+}
+
+// Fold lowercases s and removes its diacritics.
+func Fold(s string) string {
+	return FoldDiacritics(strings.ToLower(s))
+}
+
+// FoldDiacritics replaces the Latin letters with diacritics in s by their
+// base letter, keeping their case, and drops combining marks.
+func FoldDiacritics(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII {
+			return r
+		}
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		if base, ok := baseLetters[unicode.ToLower(r)]; ok {
+			if unicode.IsUpper(r) {
+				return unicode.ToUpper(base)
+			}
+			return base
+		}
+		return r
+	}, s)
+}
+
+// baseLetters maps the lowercase Latin letters with diacritics to their
+// base letter.
+var baseLetters = map[rune]rune{}
+
+func init() {
+	for base, letters := range lettersWithDiacritics {
+		for _, r := range letters {
+			baseLetters[r] = base
+		}
+	}
+}
+
+// lettersWithDiacritics lists the lowercase Latin letters decomposing into
+// a base letter and combining marks, plus đ, ł and ø.
+var lettersWithDiacritics = map[rune]string{
+	'a': "àáâãäåāăąǎǟǡǻȁȃȧḁạảấầẩẫậắằẳẵặ",
+	'b': "ḃḅḇ",
+	'c': "çćĉċčḉ",
+	'd': "ďḋḍḏḑḓđ",
+	'e': "èéêëēĕėęěȅȇȩḕḗḙḛḝẹẻẽếềểễệ",
+	'f': "ḟ",
+	'g': "ĝğġģǧǵḡ",
+	'h': "ĥȟḣḥḧḩḫẖ",
+	'i': "ìíîïĩīĭįǐȉȋḭḯỉị",
+	'j': "ĵǰ",
+	'k': "ķǩḱḳḵ",
+	'l': "ĺļľḷḹḻḽł",
+	'm': "ḿṁṃ",
+	'n': "ñńņňǹṅṇṉṋ",
+	'o': "òóôõöōŏőơǒǫǭȍȏȫȭȯȱṍṏṑṓọỏốồổỗộớờởỡợø",
+	'p': "ṕṗ",
+	'r': "ŕŗřȑȓṙṛṝṟ",
+	's': "śŝşšșṡṣṥṧṩ",
+	't': "ţťțṫṭṯṱẗ",
+	'u': "ùúûüũūŭůűųưǔǖǘǚǜȕȗṳṵṷṹṻụủứừửữự",
+	'v': "ṽṿ",
+	'w': "ŵẁẃẅẇẉẘ",
+	'x': "ẋẍ",
+	'y': "ýÿŷȳẏẙỳỵỷỹ",
+	'z': "źżžẑẓẕ",
+}
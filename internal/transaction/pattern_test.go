@@ -0,0 +1,64 @@
+package transaction
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Matches substrings, globs and regular expressions regardless of case and diacritics
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		content string
+		match   bool
+	}{
+		{"an trua", "Ăn trưa với đồng nghiệp", true},
+		{"ĐỒNG", "an trua voi dong nghiep", true},
+		{"grab*", "GRAB *Food", true},
+		{"grab*", "Paid Grab", false},
+		{"?ber", "Uber trip", false},
+		{"re:^(grab|uber)\\b", "Uber trip", true},
+		{"re:chuyển\\s+khoản", "CHUYEN  KHOAN noi bo", true},
+		{"coffee", "Café", false},
+	}
+	for _, test := range tests {
+		p, err := ParsePattern(test.pattern)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", test.pattern, err)
+		}
+		if p.Match(test.content) != test.match {
+			t.Errorf("%s on %q: expected %v", test.pattern, test.content, test.match)
+		}
+	}
+
+	if _, err := ParsePattern("re:(unclosed"); err == nil {
+		t.Errorf("expected an error for an invalid regular expression, got nil")
+	}
+}
+
+// Keeps transactions matching an include pattern and no exclude pattern
+func TestFilterContent(t *testing.T) {
+	transactions := []Transaction{
+		{Content: "Grab ride"},
+		{Content: "Chuyển khoản nội bộ Grab"},
+		{Content: "Uber trip"},
+		{Content: "Lunch"},
+	}
+	include := []Pattern{mustParsePattern(t, "grab"), mustParsePattern(t, "uber")}
+	exclude := []Pattern{mustParsePattern(t, "chuyen khoan")}
+
+	filtered := FilterContent(transactions, include, exclude)
+
+	expected := []Transaction{transactions[0], transactions[2]}
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("Expected %v, but got %v", expected, filtered)
+	}
+}
+
+func mustParsePattern(t *testing.T, s string) Pattern {
+	p, err := ParsePattern(s)
+	if err != nil {
+		t.Fatalf("failed to parse pattern %s: %v", s, err)
+	}
+	return p
+}
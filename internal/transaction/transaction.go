@@ -1,15 +1,31 @@
 package transaction
 
 import (
+	"cmp"
+	"fmt"
 	"sort"
+	"strings"
 	"time"
 )
 
 // Transaction represents a single deposit or withdrawal.
 type Transaction struct {
-	Date    string `json:"date"`
-	Amount  int    `json:"amount"`
-	Content string `json:"content"`
+	Date     string            `json:"date"`
+	Amount   int               `json:"amount"`
+	Content  string            `json:"content"`
+	Category string            `json:"category,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Source   *Source           `json:"source,omitempty"`
+}
+
+// Source locates the input record a transaction was read from. Offset is in
+// bytes from the start of the file. It is nil for input transcoded from
+// another encoding than UTF-8 and for spreadsheets, whose records have no
+// position in the bytes of the file.
+type Source struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Offset *int64 `json:"offset,omitempty"`
 }
 
 // Summary represents the JSON output structure.
@@ -18,10 +34,165 @@ type Summary struct {
 	TotalIncome      int           `json:"total_income"`
 	TotalExpenditure int           `json:"total_expenditure"`
 	Transactions     []Transaction `json:"transactions"`
+	GroupBy          string        `json:"group_by,omitempty"`
+	Groups           []Group       `json:"groups,omitempty"`
+	Filters          *Filters      `json:"filters,omitempty"`
+	Paging           *Paging       `json:"paging,omitempty"`
+}
+
+// Page selects the Limit transactions following the first Offset ones, or
+// all of them when Limit is zero.
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// Paging describes the page of transactions of a summary.
+type Paging struct {
+	// Total counts the transactions of all pages.
+	Total   int  `json:"total"`
+	Offset  int  `json:"offset"`
+	Limit   int  `json:"limit,omitempty"`
+	HasMore bool `json:"has_more"`
+}
+
+// Filters records the filters applied to the transactions besides the
+// period.
+type Filters struct {
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Include   []string          `json:"include,omitempty"`
+	Exclude   []string          `json:"exclude,omitempty"`
+	MinAmount *int              `json:"min_amount,omitempty"`
+	MaxAmount *int              `json:"max_amount,omitempty"`
+	Direction string            `json:"direction,omitempty"`
+	Where     string            `json:"where,omitempty"`
+}
+
+// Period is an inclusive interval of days, open-ended when From or To is
+// zero. Label names it in Summary.Period.
+type Period struct {
+	Label string
+	From  time.Time
+	To    time.Time
+}
+
+// Contains reports whether the day of date lies within the period.
+func (p Period) Contains(date time.Time) bool {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	return (p.From.IsZero() || !day.Before(p.From)) && (p.To.IsZero() || !day.After(p.To))
+}
+
+// Calendar tells where months and years begin, for budgets running from
+// payday to payday and for fiscal years. Months and years are named after
+// the calendar month and year they begin in. The zero value is the
+// Gregorian calendar.
+type Calendar struct {
+	// MonthStartDay is the day of the month months begin on, from 1 to 28.
+	MonthStartDay int
+	// YearStartMonth is the month years begin in.
+	YearStartMonth time.Month
+}
+
+// Fiscal reports whether years begin in another month than January. Their
+// labels then start with "FY".
+func (c Calendar) Fiscal() bool {
+	return c.yearStart() != time.January
+}
+
+// Month returns the period of the month named year/month.
+func (c Calendar) Month(year int, month time.Month) Period {
+	from := time.Date(year, month, c.monthStart(), 0, 0, 0, 0, time.UTC)
+	return Period{
+		Label: from.Format("2006/01"),
+		From:  from,
+		To:    from.AddDate(0, 1, -1),
+	}
+}
+
+// Quarter returns the period of a quarter, from 1 to 4, of the year.
+func (c Calendar) Quarter(year int, quarter int) Period {
+	from := c.Month(year, c.yearStart()+time.Month(3*(quarter-1))).From
+	return Period{
+		Label: fmt.Sprintf("%s/Q%d", c.yearLabel(year), quarter),
+		From:  from,
+		To:    from.AddDate(0, 3, -1),
+	}
+}
+
+// Year returns the period of the year.
+func (c Calendar) Year(year int) Period {
+	from := c.Month(year, c.yearStart()).From
+	return Period{
+		Label: c.yearLabel(year),
+		From:  from,
+		To:    from.AddDate(1, 0, -1),
+	}
+}
+
+// MonthOf returns the month holding the day of date.
+func (c Calendar) MonthOf(date time.Time) Period {
+	month := date.Month()
+	if date.Day() < c.monthStart() {
+		month--
+	}
+	start := time.Date(date.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+	return c.Month(start.Year(), start.Month())
+}
+
+// QuarterOf returns the quarter holding the day of date.
+func (c Calendar) QuarterOf(date time.Time) Period {
+	month := c.MonthOf(date).From
+	months := (int(month.Month()) - int(c.yearStart()) + 12) % 12
+	return c.Quarter(month.AddDate(0, -months, 0).Year(), months/3+1)
+}
+
+// YearOf returns the year holding the day of date.
+func (c Calendar) YearOf(date time.Time) Period {
+	month := c.MonthOf(date).From
+	months := (int(month.Month()) - int(c.yearStart()) + 12) % 12
+	return c.Year(month.AddDate(0, -months, 0).Year())
+}
+
+func (c Calendar) monthStart() int {
+	return max(c.MonthStartDay, 1)
 }
 
-// FilterTransactions filters transactions based on the specified year and month.
-func FilterTransactions(transactions []Transaction, year int, month time.Month) []Transaction {
+func (c Calendar) yearStart() time.Month {
+	return max(c.YearStartMonth, time.January)
+}
+
+func (c Calendar) yearLabel(year int) string {
+	if c.Fiscal() {
+		return fmt.Sprintf("FY%04d", year)
+	}
+	return fmt.Sprintf("%04d", year)
+}
+
+// Field returns the value of a named field: date, content, category or a
+// metadata key. Names compare regardless of case and spacing.
+func (tx Transaction) Field(name string) (string, bool) {
+	name = MetadataKey(name)
+	switch name {
+	case "date":
+		return tx.Date, true
+	case "content":
+		return tx.Content, true
+	case "category":
+		return tx.Category, tx.Category != ""
+	}
+	value, ok := tx.Metadata[name]
+	return value, ok
+}
+
+// MetadataKey normalizes the name of a metadata key. Importers store
+// metadata under normalized keys, so that keys compare regardless of case
+// and spacing.
+func MetadataKey(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// FilterTransactions keeps the transactions dated within the period.
+func FilterTransactions(transactions []Transaction, period Period) []Transaction {
 	var filtered []Transaction
 
 	for _, tx := range transactions {
@@ -30,7 +201,7 @@ func FilterTransactions(transactions []Transaction, year int, month time.Month)
 			continue
 		}
 
-		if txDate.Year() == year && txDate.Month() == month {
+		if period.Contains(txDate) {
 			filtered = append(filtered, tx)
 		}
 	}
@@ -38,6 +209,104 @@ func FilterTransactions(transactions []Transaction, year int, month time.Month)
 	return filtered
 }
 
+// FilterMetadata keeps the transactions whose fields have all the given values.
+func FilterMetadata(transactions []Transaction, values map[string]string) []Transaction {
+	if len(values) == 0 {
+		return transactions
+	}
+
+	var filtered []Transaction
+	for _, tx := range transactions {
+		matches := true
+		for name, expected := range values {
+			if value, ok := tx.Field(name); !ok || value != expected {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			filtered = append(filtered, tx)
+		}
+	}
+
+	return filtered
+}
+
+// Directions of an AmountFilter.
+const (
+	DirectionIncome  = "income"
+	DirectionExpense = "expense"
+)
+
+// AmountFilter selects transactions by direction and by the size of their
+// amount, regardless of its sign. Nil bounds are not applied.
+type AmountFilter struct {
+	Min       *int
+	Max       *int
+	Direction string
+}
+
+// Match reports whether an amount passes the filter.
+func (f AmountFilter) Match(amount int) bool {
+	switch {
+	case f.Direction == DirectionIncome && amount <= 0:
+		return false
+	case f.Direction == DirectionExpense && amount >= 0:
+		return false
+	}
+	size := max(amount, -amount)
+	return (f.Min == nil || size >= *f.Min) && (f.Max == nil || size <= *f.Max)
+}
+
+// FilterAmount keeps the transactions whose amount passes the filter.
+func FilterAmount(transactions []Transaction, filter AmountFilter) []Transaction {
+	if filter == (AmountFilter{}) {
+		return transactions
+	}
+
+	var filtered []Transaction
+	for _, tx := range transactions {
+		if filter.Match(tx.Amount) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
+
+// SummarizeMonths splits transactions into one summary per month of the
+// calendar, in ascending order of month. The transactions keep their order
+// within a month, and are grouped by the groupBy field unless it is empty.
+func SummarizeMonths(transactions []Transaction, groupBy string, calendar Calendar) []Summary {
+	var months []Summary
+	index := map[string]int{}
+	for _, tx := range transactions {
+		txDate, err := time.Parse("2006/01/02", tx.Date)
+		if err != nil {
+			continue
+		}
+		period := calendar.MonthOf(txDate).Label
+		i, ok := index[period]
+		if !ok {
+			i = len(months)
+			index[period] = i
+			months = append(months, Summary{Period: period})
+		}
+		months[i].Transactions = append(months[i].Transactions, tx)
+	}
+
+	sort.Slice(months, func(i, j int) bool {
+		return months[i].Period < months[j].Period
+	})
+	for i := range months {
+		months[i].TotalIncome, months[i].TotalExpenditure = CalculateTotals(months[i].Transactions)
+		if groupBy != "" {
+			months[i].GroupBy = groupBy
+			months[i].Groups = GroupTransactions(months[i].Transactions, groupBy)
+		}
+	}
+	return months
+}
+
 // CalculateTotals calculates the total income and total expenditure.
 func CalculateTotals(transactions []Transaction) (int, int) {
 	totalIncome := 0
@@ -54,11 +323,89 @@ func CalculateTotals(transactions []Transaction) (int, int) {
 	return totalIncome, totalExpenditure
 }
 
-// SortTransactions sorts transactions in descending order by date.
-func SortTransactions(transactions []Transaction) {
-	sort.Slice(transactions, func(i, j int) bool {
-		dateI, _ := time.Parse("2006/01/02", transactions[i].Date)
-		dateJ, _ := time.Parse("2006/01/02", transactions[j].Date)
-		return dateI.After(dateJ)
+// Apply returns the transactions of the page and describes it.
+func (p Page) Apply(transactions []Transaction) ([]Transaction, *Paging) {
+	paging := &Paging{Total: len(transactions), Offset: p.Offset, Limit: p.Limit}
+	start := min(p.Offset, len(transactions))
+	end := len(transactions)
+	if p.Limit > 0 {
+		end = min(start+p.Limit, end)
+	}
+	paging.HasMore = end < len(transactions)
+	return transactions[start:end], paging
+}
+
+// SortKey orders transactions by a field: date, amount, abs_amount,
+// content, category or a metadata key. Strings are compared regardless of
+// case and diacritics.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultOrder sorts transactions by date, newest first.
+var DefaultOrder = []SortKey{{Field: "date", Desc: true}}
+
+// SortTransactions sorts transactions by the keys of the order, or by
+// DefaultOrder when it is empty. Transactions comparing equal keep their
+// order.
+func SortTransactions(transactions []Transaction, order []SortKey) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return CompareTransactions(transactions[i], transactions[j], order) < 0
 	})
 }
+
+// MergeSorted merges two lists of transactions sorted by the order into
+// one, taking transactions of a before equal ones of b. Merging the sorted
+// parts of a file in file order gives the order of the sorted file.
+func MergeSorted(a, b []Transaction, order []SortKey) []Transaction {
+	merged := make([]Transaction, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if CompareTransactions(b[0], a[0], order) < 0 {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// CompareTransactions compares two transactions by the keys of the order,
+// or by DefaultOrder when it is empty.
+func CompareTransactions(a, b Transaction, order []SortKey) int {
+	if len(order) == 0 {
+		order = DefaultOrder
+	}
+	for _, key := range order {
+		c := compareField(a, b, key.Field)
+		if key.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareField(a, b Transaction, field string) int {
+	switch field {
+	case "amount":
+		return cmp.Compare(a.Amount, b.Amount)
+	case "abs_amount":
+		return cmp.Compare(max(a.Amount, -a.Amount), max(b.Amount, -b.Amount))
+	case "date":
+		dateA, _ := time.Parse("2006/01/02", a.Date)
+		dateB, _ := time.Parse("2006/01/02", b.Date)
+		return dateA.Compare(dateB)
+	}
+	valueA, _ := a.Field(field)
+	valueB, _ := b.Field(field)
+	if c := strings.Compare(Fold(valueA), Fold(valueB)); c != 0 {
+		return c
+	}
+	return strings.Compare(valueA, valueB)
+}
@@ -15,10 +15,12 @@ func TestFilterTransactionsCorrectYearMonth(t *testing.T) {
 		{Date: "2022/06/10"},
 	}
 
-	year := 2023
-	month := time.June
+	june := Period{
+		From: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC),
+	}
 
-	filtered := FilterTransactions(transactions, year, month)
+	filtered := FilterTransactions(transactions, june)
 
 	expected := []Transaction{
 		{Date: "2023/06/20"},
@@ -30,6 +32,31 @@ func TestFilterTransactionsCorrectYearMonth(t *testing.T) {
 	}
 }
 
+// Includes both ends of the interval and supports open ends
+func TestFilterTransactionsInterval(t *testing.T) {
+	transactions := []Transaction{
+		{Date: "2023/03/14"},
+		{Date: "2023/03/15"},
+		{Date: "2023/04/14"},
+		{Date: "2023/04/15"},
+	}
+
+	period := Period{
+		From: time.Date(2023, time.March, 15, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2023, time.April, 14, 0, 0, 0, 0, time.UTC),
+	}
+	expected := []Transaction{{Date: "2023/03/15"}, {Date: "2023/04/14"}}
+	if filtered := FilterTransactions(transactions, period); !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("Expected %v, but got %v", expected, filtered)
+	}
+
+	period.To = time.Time{}
+	expected = transactions[1:]
+	if filtered := FilterTransactions(transactions, period); !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("Expected %v, but got %v", expected, filtered)
+	}
+}
+
 // Handles transactions with invalid date formats gracefully
 func TestFilterTransactionsInvalidDateFormat(t *testing.T) {
 	transactions := []Transaction{
@@ -38,10 +65,12 @@ func TestFilterTransactionsInvalidDateFormat(t *testing.T) {
 		{Date: "2023/06/25"},
 	}
 
-	year := 2023
-	month := time.June
+	june := Period{
+		From: time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2023, time.June, 30, 0, 0, 0, 0, time.UTC),
+	}
 
-	filtered := FilterTransactions(transactions, year, month)
+	filtered := FilterTransactions(transactions, june)
 
 	expected := []Transaction{
 		{Date: "2023/06/25"},
@@ -92,7 +121,7 @@ func TestSortTransactionsDescendingOrder(t *testing.T) {
 		{Date: "2023/10/05"},
 	}
 
-	SortTransactions(transactions)
+	SortTransactions(transactions, nil)
 
 	expectedOrder := []string{"2023/10/05", "2023/10/01", "2023/09/15"}
 	for i, transaction := range transactions {
@@ -101,3 +130,171 @@ func TestSortTransactionsDescendingOrder(t *testing.T) {
 		}
 	}
 }
+
+// Sorts by several keys and merges sorted parts in the same order
+func TestSortTransactionsKeys(t *testing.T) {
+	transactions := []Transaction{
+		{Date: "2023/10/01", Amount: -50, Content: "b"},
+		{Date: "2023/09/15", Amount: -200, Content: "Ăn"},
+		{Date: "2023/10/05", Amount: -50, Content: "a"},
+		{Date: "2023/10/05", Amount: 100, Content: "c"},
+		{Date: "2023/09/15", Amount: -50, Content: "d"},
+	}
+	order := []SortKey{{Field: "amount"}, {Field: "date", Desc: true}}
+
+	sorted := append([]Transaction(nil), transactions...)
+	SortTransactions(sorted, order)
+
+	expected := []string{"Ăn", "a", "b", "d", "c"}
+	for i, tx := range sorted {
+		if tx.Content != expected[i] {
+			t.Errorf("Expected %s at %d but got %s", expected[i], i, tx.Content)
+		}
+	}
+
+	a, b := append([]Transaction(nil), transactions[:2]...), append([]Transaction(nil), transactions[2:]...)
+	SortTransactions(a, order)
+	SortTransactions(b, order)
+	if merged := MergeSorted(a, b, order); !reflect.DeepEqual(merged, sorted) {
+		t.Errorf("Expected merged parts %v, but got %v", sorted, merged)
+	}
+
+	SortTransactions(sorted, []SortKey{{Field: "content"}})
+	if sorted[0].Content != "a" || sorted[1].Content != "Ăn" {
+		t.Errorf("Expected content sorted regardless of diacritics, but got %v", sorted)
+	}
+}
+
+// Selects a page of transactions and tells whether more follow
+func TestPageApply(t *testing.T) {
+	transactions := []Transaction{{Amount: 1}, {Amount: 2}, {Amount: 3}, {Amount: 4}, {Amount: 5}}
+
+	page, paging := Page{Offset: 1, Limit: 2}.Apply(transactions)
+	if !reflect.DeepEqual(page, transactions[1:3]) || *paging != (Paging{Total: 5, Offset: 1, Limit: 2, HasMore: true}) {
+		t.Errorf("Expected the 2nd and 3rd transactions with more to follow, but got %v %+v", page, paging)
+	}
+
+	page, paging = Page{Offset: 4, Limit: 2}.Apply(transactions)
+	if len(page) != 1 || paging.HasMore {
+		t.Errorf("Expected the last transaction alone, but got %v %+v", page, paging)
+	}
+	if page, _ := (Page{Offset: 9}).Apply(transactions); len(page) != 0 {
+		t.Errorf("Expected no transactions past the end, but got %v", page)
+	}
+}
+
+// Keeps only transactions matching every metadata value
+func TestFilterMetadata(t *testing.T) {
+	transactions := []Transaction{
+		{Content: "a", Metadata: map[string]string{"channel": "ATM", "branch": "HN"}},
+		{Content: "b", Metadata: map[string]string{"channel": "ATM", "branch": "HCM"}},
+		{Content: "c", Metadata: map[string]string{"channel": "POS"}},
+		{Content: "d"},
+	}
+
+	filtered := FilterMetadata(transactions, map[string]string{"Channel": "ATM", " branch": "HN"})
+
+	if len(filtered) != 1 || filtered[0].Content != "a" {
+		t.Errorf("Expected only transaction a, but got %v", filtered)
+	}
+}
+
+// Keeps expenses within bounds on the absolute amount
+func TestFilterAmount(t *testing.T) {
+	transactions := []Transaction{
+		{Amount: -2000000},
+		{Amount: -500000},
+		{Amount: 3000000},
+		{Amount: -1000000},
+	}
+
+	bound := func(n int) *int { return &n }
+	filtered := FilterAmount(transactions, AmountFilter{Min: bound(1000000), Direction: DirectionExpense})
+
+	expected := []Transaction{transactions[0], transactions[3]}
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("Expected %v, but got %v", expected, filtered)
+	}
+	if filtered := FilterAmount(transactions, AmountFilter{Max: bound(600000)}); len(filtered) != 1 {
+		t.Errorf("Expected one transaction up to 600000, but got %v", filtered)
+	}
+	if filtered := FilterAmount(append(transactions, Transaction{}), AmountFilter{Max: bound(0)}); len(filtered) != 1 || filtered[0].Amount != 0 {
+		t.Errorf("Expected only the transaction of 0, but got %v", filtered)
+	}
+}
+
+// Shifts months to the start day and years to the fiscal start month
+func TestCalendar(t *testing.T) {
+	calendar := Calendar{MonthStartDay: 25, YearStartMonth: time.April}
+	day := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		period   Period
+		expected Period
+	}{
+		{calendar.Month(2023, time.December), Period{"2023/12", day(2023, 12, 25), day(2024, 1, 24)}},
+		{calendar.MonthOf(day(2024, 1, 10)), Period{"2023/12", day(2023, 12, 25), day(2024, 1, 24)}},
+		{calendar.Year(2023), Period{"FY2023", day(2023, 4, 25), day(2024, 4, 24)}},
+		{calendar.YearOf(day(2023, 4, 24)), Period{"FY2022", day(2022, 4, 25), day(2023, 4, 24)}},
+		{calendar.Quarter(2023, 4), Period{"FY2023/Q4", day(2024, 1, 25), day(2024, 4, 24)}},
+		{calendar.QuarterOf(day(2024, 1, 10)), Period{"FY2023/Q3", day(2023, 10, 25), day(2024, 1, 24)}},
+		{Calendar{}.QuarterOf(day(2024, 1, 10)), Period{"2024/Q1", day(2024, 1, 1), day(2024, 3, 31)}},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.period, test.expected) {
+			t.Errorf("Expected %v, but got %v", test.expected, test.period)
+		}
+	}
+}
+
+// Splits transactions into summaries per month in ascending order
+func TestSummarizeMonths(t *testing.T) {
+	transactions := []Transaction{
+		{Date: "2023/02/10", Amount: -30, Content: "lunch", Category: "food"},
+		{Date: "2023/01/31", Amount: 100, Content: "salary"},
+		{Date: "2023/01/02", Amount: -20, Content: "coffee", Category: "food"},
+	}
+
+	months := SummarizeMonths(transactions, "category", Calendar{})
+
+	expected := []Summary{
+		{
+			Period:           "2023/01",
+			TotalIncome:      100,
+			TotalExpenditure: -20,
+			Transactions:     transactions[1:],
+			GroupBy:          "category",
+			Groups: []Group{
+				{Value: "", Count: 1, TotalIncome: 100, Sum: 100, Average: 100, Min: 100, Max: 100, IncomeShare: 1},
+				{Value: "food", Count: 1, TotalExpenditure: -20, Sum: -20, Average: -20, Min: -20, Max: -20, ExpenditureShare: 1},
+			},
+		},
+		{
+			Period:           "2023/02",
+			TotalExpenditure: -30,
+			Transactions:     transactions[:1],
+			GroupBy:          "category",
+			Groups:           []Group{{Value: "food", Count: 1, TotalExpenditure: -30, Sum: -30, Average: -30, Min: -30, Max: -30, ExpenditureShare: 1}},
+		},
+	}
+	if !reflect.DeepEqual(months, expected) {
+		t.Errorf("Expected %v, but got %v", expected, months)
+	}
+}
+
+// Names the months of summaries after the day they begin on
+func TestSummarizeMonthsShifted(t *testing.T) {
+	transactions := []Transaction{
+		{Date: "2023/02/10", Amount: -30},
+		{Date: "2023/01/25", Amount: 100},
+		{Date: "2023/01/24", Amount: -20},
+	}
+
+	months := SummarizeMonths(transactions, "", Calendar{MonthStartDay: 25})
+
+	if len(months) != 2 || months[0].Period != "2022/12" || months[1].Period != "2023/01" || len(months[1].Transactions) != 2 {
+		t.Errorf("Expected summaries for 2022/12 and 2023/01, but got %v", months)
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/tonghia/transaction-history/internal/args"
+	"github.com/tonghia/transaction-history/internal/config"
+	"github.com/tonghia/transaction-history/internal/parser"
 	"github.com/tonghia/transaction-history/internal/processor"
+	"github.com/tonghia/transaction-history/internal/query"
 	"github.com/tonghia/transaction-history/internal/transaction"
 )
 
@@ -37,7 +43,12 @@ func TestSummaryGeneration(t *testing.T) {
 		t.Fatalf("Failed to unmarshal expected summary JSON: %v", err)
 	}
 
-	generatedSummaryData, err := processor.Process(transactionsFilePath, testPeriod, 1)
+	period, err := parser.ParsePeriod(testPeriod, transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+
+	generatedSummaryData, err := processor.Process(transactionsFilePath, period, 1, processor.Options{})
 	if err != nil {
 		t.Fatalf("Failed to generate summary: %v", err)
 	}
@@ -82,3 +93,442 @@ func TestSummaryGeneration(t *testing.T) {
 		}
 	}
 }
+
+// TestProvenance checks that every transaction points back to its input
+// line, with one worker and with several.
+func TestProvenance(t *testing.T) {
+	transactionsFilePath := filepath.Join("testdata", "transactions.csv")
+	data, err := os.ReadFile(transactionsFilePath)
+	if err != nil {
+		t.Fatalf("Failed to read transactions file: %v", err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	period, err := parser.ParsePeriod("202201", transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+
+	for _, workerNum := range []int{1, 3} {
+		opts := processor.Options{Parser: parser.Options{Provenance: true}}
+		generatedSummaryData, err := processor.Process(transactionsFilePath, period, workerNum, opts)
+		if err != nil {
+			t.Fatalf("Failed to generate summary: %v", err)
+		}
+
+		var generatedSummary transaction.Summary
+		if err := json.Unmarshal(generatedSummaryData, &generatedSummary); err != nil {
+			t.Fatalf("Failed to unmarshal generated summary JSON: %v", err)
+		}
+
+		for _, tx := range generatedSummary.Transactions {
+			source := tx.Source
+			if source == nil || source.File != transactionsFilePath {
+				t.Fatalf("workernum %d: missing source for %v", workerNum, tx)
+			}
+			line := lines[source.Line-1]
+			if source.Offset == nil || !strings.HasPrefix(string(data[*source.Offset:]), line) || !strings.HasPrefix(line, tx.Date) {
+				t.Errorf("workernum %d: source %v does not point to %v", workerNum, *source, tx)
+			}
+		}
+	}
+}
+
+// summarize processes a file of testdata for the period with one worker
+// and with several, checks that both give the same output and unmarshals it
+// into result.
+func summarize(t *testing.T, file string, period string, opts processor.Options, result any) {
+	t.Helper()
+	parsedPeriod, err := parser.ParsePeriod(period, transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+
+	var outputs []json.RawMessage
+	for _, workerNum := range []int{1, 3} {
+		output, err := processor.Process(filepath.Join("testdata", file), parsedPeriod, workerNum, opts)
+		if err != nil {
+			t.Fatalf("Failed to generate summary with %d workers: %v", workerNum, err)
+		}
+		outputs = append(outputs, output)
+	}
+	if string(outputs[0]) != string(outputs[1]) {
+		t.Errorf("Output mismatch between workers: %s and %s", outputs[0], outputs[1])
+	}
+
+	if err := json.Unmarshal(outputs[0], result); err != nil {
+		t.Fatalf("Failed to unmarshal generated JSON: %v", err)
+	}
+}
+
+// TestPeriodWorkers checks that a quarter gives the same summary with one
+// worker and with several.
+func TestPeriodWorkers(t *testing.T) {
+	var summary transaction.Summary
+	summarize(t, "transactions.csv", "2022Q1", processor.Options{}, &summary)
+
+	if summary.Period != "2022/Q1" || len(summary.Transactions) != 4 {
+		t.Errorf("expected the 4 transactions of 2022/Q1, got %v", summary)
+	}
+}
+
+// TestMonthlyWorkers checks that the summaries per month add up to the
+// grand total.
+func TestMonthlyWorkers(t *testing.T) {
+	var result processor.MonthlyResult
+	summarize(t, "transactions.csv", "all", processor.Options{}, &result)
+
+	totalIncome, totalExpenditure := 0, 0
+	for i, month := range result.Months {
+		if i > 0 && month.Period <= result.Months[i-1].Period {
+			t.Errorf("Months out of order: %s after %s", month.Period, result.Months[i-1].Period)
+		}
+		totalIncome += month.TotalIncome
+		totalExpenditure += month.TotalExpenditure
+	}
+	if totalIncome != result.TotalIncome || totalExpenditure != result.TotalExpenditure {
+		t.Errorf("Monthly totals %d/%d do not add up to %d/%d", totalIncome, totalExpenditure, result.TotalIncome, result.TotalExpenditure)
+	}
+}
+
+// TestContentFilters checks that totals only cover the transactions kept by
+// the content filters, which ignore case and diacritics.
+func TestContentFilters(t *testing.T) {
+	include, err := transaction.ParsePattern("re:^(e|d)")
+	if err != nil {
+		t.Fatalf("Failed to parse pattern: %v", err)
+	}
+	exclude, err := transaction.ParsePattern("DÉBIT")
+	if err != nil {
+		t.Fatalf("Failed to parse pattern: %v", err)
+	}
+	opts := processor.Options{
+		Include: []transaction.Pattern{include},
+		Exclude: []transaction.Pattern{exclude},
+	}
+
+	var summary transaction.Summary
+	summarize(t, "transactions.csv", "2022Q1", opts, &summary)
+
+	if len(summary.Transactions) != 2 || summary.TotalExpenditure != -2500 {
+		t.Errorf("expected 2 transactions totalling -2500, got %v", summary)
+	}
+}
+
+// TestAmountFilters checks that totals only cover the selected amounts and
+// that the summary notes the active filters.
+func TestAmountFilters(t *testing.T) {
+	amount, err := args.ParseAmountFilter("5,000", "", false, true)
+	if err != nil {
+		t.Fatalf("Failed to parse amount filter: %v", err)
+	}
+
+	var summary transaction.Summary
+	summarize(t, "transactions.csv", "2022Q1", processor.Options{Amount: amount}, &summary)
+
+	if len(summary.Transactions) != 2 || summary.TotalExpenditure != -110000 {
+		t.Errorf("expected 2 transactions totalling -110000, got %v", summary)
+	}
+	if summary.Filters == nil || summary.Filters.MinAmount == nil || *summary.Filters.MinAmount != 5000 || summary.Filters.Direction != transaction.DirectionExpense {
+		t.Errorf("expected the minimum amount and direction to be noted, got %v", summary.Filters)
+	}
+}
+
+// TestWhereFilter checks that -where expressions filter the transactions of
+// every part before totalling.
+func TestWhereFilter(t *testing.T) {
+	where, err := query.Parse(`content ~ "^d" and amount > -5000`)
+	if err != nil {
+		t.Fatalf("Failed to parse expression: %v", err)
+	}
+
+	var summary transaction.Summary
+	summarize(t, "transactions.csv", "2022Q1", processor.Options{Where: where}, &summary)
+
+	if len(summary.Transactions) != 1 || summary.TotalExpenditure != -1500 {
+		t.Errorf("expected the dining out transaction alone, got %v", summary)
+	}
+	if summary.Filters == nil || summary.Filters.Where != where.String() {
+		t.Errorf("expected the where filter to be noted, got %v", summary.Filters)
+	}
+}
+
+// TestSortWorkers checks that the parallel merge keeps the requested order.
+func TestSortWorkers(t *testing.T) {
+	opts := processor.Options{
+		Sort: []transaction.SortKey{{Field: "amount"}, {Field: "date", Desc: true}},
+	}
+
+	var summary transaction.Summary
+	summarize(t, "transactions.csv", "2022-01-01..2023-12-31", opts, &summary)
+
+	var content []string
+	for _, tx := range summary.Transactions {
+		content = append(content, tx.Content)
+	}
+	expected := []string{"rent", "debit", "dining out", "eating out", "transportation", "salary"}
+	if !reflect.DeepEqual(content, expected) {
+		t.Errorf("expected %v, got %v", expected, content)
+	}
+}
+
+// TestTopPaging checks that -top outputs the largest transactions while
+// totals and the paging metadata cover all matching ones.
+func TestTopPaging(t *testing.T) {
+	page, order, err := args.ParsePage(0, 1, 2, nil)
+	if err != nil {
+		t.Fatalf("Failed to parse paging: %v", err)
+	}
+
+	var summary transaction.Summary
+	summarize(t, "transactions.csv", "2022-01-01..2023-12-31", processor.Options{Sort: order, Page: page}, &summary)
+
+	if len(summary.Transactions) != 2 || summary.Transactions[0].Content != "rent" || summary.Transactions[1].Content != "debit" {
+		t.Errorf("expected rent and debit, got %v", summary.Transactions)
+	}
+	if summary.TotalIncome != 200000 || summary.TotalExpenditure != -113220 {
+		t.Errorf("expected totals over all transactions, got %d and %d", summary.TotalIncome, summary.TotalExpenditure)
+	}
+	expected := &transaction.Paging{Total: 6, Offset: 1, Limit: 2, HasMore: true}
+	if !reflect.DeepEqual(summary.Paging, expected) {
+		t.Errorf("expected paging %v, got %v", expected, summary.Paging)
+	}
+}
+
+// TestGroupByWorkers checks that groups merged from the parts of a file
+// aggregate all its transactions.
+func TestGroupByWorkers(t *testing.T) {
+	var summary transaction.Summary
+	summarize(t, "transactions.csv", "2022-01-01..2023-12-31", processor.Options{GroupBy: "content"}, &summary)
+
+	if len(summary.Groups) != 6 {
+		t.Fatalf("expected a group per content, got %v", summary.Groups)
+	}
+	if rent := summary.Groups[3]; rent.Value != "rent" || rent.Sum != -100000 || rent.ExpenditureShare != 0.8832 {
+		t.Errorf("expected rent to hold 88.32%% of the expenditure, got %+v", rent)
+	}
+}
+
+// TestProvenanceTranscoded checks that transcoded input reports lines but no
+// byte offsets.
+func TestProvenanceTranscoded(t *testing.T) {
+	opts := processor.Options{Parser: parser.Options{Provenance: true}}
+
+	var summary transaction.Summary
+	summarize(t, "transactions_utf16.csv", "202201", opts, &summary)
+
+	data, err := os.ReadFile(filepath.Join("testdata", "transactions.csv"))
+	if err != nil {
+		t.Fatalf("Failed to read transactions file: %v", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	if len(summary.Transactions) != 3 {
+		t.Fatalf("expected 3 transactions, got %v", summary.Transactions)
+	}
+	for _, tx := range summary.Transactions {
+		if tx.Source == nil || tx.Source.Offset != nil || !strings.HasPrefix(lines[tx.Source.Line-1], tx.Date) {
+			t.Errorf("expected the line of %v without offset, got %v", tx, tx.Source)
+		}
+	}
+}
+
+// TestProfileEncoding checks that the input is decoded with the encoding of
+// the detected profile, even when the start of the file is plain ASCII.
+func TestProfileEncoding(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "export.csv")
+	data := "Datum;Betrag;Text\n" + strings.Repeat("2022-01-01;-1;fee\n", 4000) + "2022-01-02;-5;Caf\xe9\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write export file: %v", err)
+	}
+
+	period, err := parser.ParsePeriod("202201", transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+	profile := config.Profile{
+		Name:       "bank",
+		Delimiter:  ";",
+		DateLayout: "2006-01-02",
+		Encoding:   parser.EncodingWindows1252,
+		Columns:    map[string]string{"datum": "date", "betrag": "amount", "text": "content"},
+	}
+
+	generatedSummaryData, err := processor.Process(filePath, period, 1, processor.Options{Profiles: []config.Profile{profile}})
+	if err != nil {
+		t.Fatalf("Failed to generate summary: %v", err)
+	}
+
+	var generatedSummary transaction.Summary
+	if err := json.Unmarshal(generatedSummaryData, &generatedSummary); err != nil {
+		t.Fatalf("Failed to unmarshal generated summary JSON: %v", err)
+	}
+	if n := len(generatedSummary.Transactions); n != 4001 || generatedSummary.Transactions[0].Content != "Café" {
+		t.Errorf("expected 4001 transactions starting with Café, got %d starting with %v", n, generatedSummary.Transactions[0])
+	}
+}
+
+// TestWorkerErrors checks that an error in one part of a split file is
+// returned instead of ending the process.
+func TestWorkerErrors(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "transactions.csv")
+	data := "date,amount,content\n" + strings.Repeat("2022/01/05,-1000,eating out\n", 20) + strings.Repeat("bad,1,a\n", 5)
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write transactions file: %v", err)
+	}
+
+	period, err := parser.ParsePeriod("202201", transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+
+	opts := processor.Options{Parser: parser.Options{Lenient: true, MaxErrors: 2}}
+	_, err = processor.Process(filePath, period, 3, opts)
+	if err == nil || !strings.Contains(err.Error(), parser.ErrTooManyErrors.Error()) {
+		t.Errorf("expected %v, got %v", parser.ErrTooManyErrors, err)
+	}
+}
+
+// TestMetadataKeys checks that -meta and -group-by find JSON metadata
+// whatever the case of its keys.
+func TestMetadataKeys(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "transactions.ndjson")
+	data := `{"date": "2022/01/05", "amount": -1000, "content": "eating out", "metadata": {"Channel": "ATM"}}
+{"date": "2022/01/06", "amount": -500, "content": "coffee", "metadata": {"Channel": "POS"}}
+`
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write transactions file: %v", err)
+	}
+
+	period, err := parser.ParsePeriod("202201", transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+	metadata, err := args.ParseMetadataFilters([]string{"Channel=ATM"})
+	if err != nil {
+		t.Fatalf("Failed to parse metadata filters: %v", err)
+	}
+
+	output, err := processor.Process(filePath, period, 1, processor.Options{Metadata: metadata, GroupBy: transaction.MetadataKey("Channel")})
+	if err != nil {
+		t.Fatalf("Failed to generate summary: %v", err)
+	}
+	var summary transaction.Summary
+	if err := json.Unmarshal(output, &summary); err != nil {
+		t.Fatalf("Failed to unmarshal generated JSON: %v", err)
+	}
+
+	if len(summary.Transactions) != 1 || len(summary.Groups) != 1 || summary.Groups[0].Value != "ATM" {
+		t.Errorf("expected the ATM transaction in its own group, got %v", summary)
+	}
+}
+
+// TestWhereMetadataHeader checks that -where finds the metadata of a CSV
+// column whatever the case of its header.
+func TestWhereMetadataHeader(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "transactions.csv")
+	data := "date,amount,content,Reference\n2022/01/05,-1000,eating out,ABC\n2022/01/06,-500,coffee,\n"
+	if err := os.WriteFile(filePath, []byte(data), 0o644); err != nil {
+		t.Fatalf("Failed to write transactions file: %v", err)
+	}
+
+	period, err := parser.ParsePeriod("202201", transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+
+	for _, expr := range []string{`meta.Reference = abc`, `meta.reference exists`} {
+		where, err := query.Parse(expr)
+		if err != nil {
+			t.Fatalf("Failed to parse expression: %v", err)
+		}
+		output, err := processor.Process(filePath, period, 1, processor.Options{Where: where})
+		if err != nil {
+			t.Fatalf("Failed to generate summary: %v", err)
+		}
+		var summary transaction.Summary
+		if err := json.Unmarshal(output, &summary); err != nil {
+			t.Fatalf("Failed to unmarshal generated JSON: %v", err)
+		}
+		if len(summary.Transactions) != 1 || summary.Transactions[0].Content != "eating out" {
+			t.Errorf("%s: expected the eating out transaction alone, got %v", expr, summary.Transactions)
+		}
+	}
+}
+
+// TestRejectsUnsupported checks that -rejects is refused for input whose
+// records cannot be copied as rows.
+func TestRejectsUnsupported(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "transactions.json")
+	if err := os.WriteFile(filePath, []byte(`[{"date": "2022/01/05", "amount": -1000, "content": "eating out"}]`), 0o644); err != nil {
+		t.Fatalf("Failed to write transactions file: %v", err)
+	}
+
+	period, err := parser.ParsePeriod("202201", transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+
+	var out strings.Builder
+	opts := processor.Options{Parser: parser.Options{Lenient: true, Rejects: parser.NewRejectWriter(&out)}}
+	if _, err := processor.Process(filePath, period, 1, opts); err == nil || !strings.Contains(err.Error(), "json") {
+		t.Errorf("expected an error about json input, got %v", err)
+	}
+}
+
+// TestXLSX checks that spreadsheet rows go through the CSV pipeline, with
+// metadata columns, row numbers and rejected rows.
+func TestXLSX(t *testing.T) {
+	period, err := parser.ParsePeriod("202201", transaction.Calendar{})
+	if err != nil {
+		t.Fatalf("Failed to parse period: %v", err)
+	}
+	metadata, err := args.ParseMetadataFilters([]string{"Channel=ATM"})
+	if err != nil {
+		t.Fatalf("Failed to parse metadata filters: %v", err)
+	}
+
+	var rejects strings.Builder
+	opts := processor.Options{
+		Parser:   parser.Options{Lenient: true, Provenance: true, Rejects: parser.NewRejectWriter(&rejects)},
+		Metadata: metadata,
+	}
+	output, err := processor.Process(filepath.Join("testdata", "transactions.xlsx"), period, 3, opts)
+	if err != nil {
+		t.Fatalf("Failed to generate summary: %v", err)
+	}
+	var result processor.Result
+	if err := json.Unmarshal(output, &result); err != nil {
+		t.Fatalf("Failed to unmarshal generated JSON: %v", err)
+	}
+
+	if len(result.Transactions) != 1 || result.Transactions[0].Content != "eating out" || result.Transactions[0].Date != "2022/01/05" {
+		t.Fatalf("expected the eating out transaction alone, got %v", result.Transactions)
+	}
+	if source := result.Transactions[0].Source; source == nil || source.Line != 3 || source.Offset != nil {
+		t.Errorf("expected row 3 without offset, got %v", source)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Line != 5 || result.Errors[0].Column != "amount" {
+		t.Errorf("expected an amount error at row 5, got %v", result.Errors)
+	}
+	if !strings.HasPrefix(rejects.String(), "2022/01/12,-12.5,snack,ATM,") {
+		t.Errorf("expected the rejected row, got %q", rejects.String())
+	}
+
+	// Profiles apply to the converted rows whatever their delimiter.
+	profile := config.Profile{
+		Name:      "sheet",
+		Delimiter: ";",
+		Columns:   map[string]string{"date": "date", "amount": "amount", "content": "content", "channel": "skip"},
+	}
+	output, err = processor.Process(filepath.Join("testdata", "transactions.xlsx"), period, 1, processor.Options{Parser: parser.Options{Lenient: true}, Profile: &profile})
+	if err != nil {
+		t.Fatalf("Failed to generate summary with a profile: %v", err)
+	}
+	var summary transaction.Summary
+	if err := json.Unmarshal(output, &summary); err != nil {
+		t.Fatalf("Failed to unmarshal generated JSON: %v", err)
+	}
+	if len(summary.Transactions) != 2 || summary.Transactions[0].Metadata != nil {
+		t.Errorf("expected 2 transactions without metadata, got %v", summary.Transactions)
+	}
+}